@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/gotid/god/api"
 	"github.com/gotid/god/lib/store/cache"
 )
@@ -10,4 +12,11 @@ type Config struct {
 
 	MySQL string
 	Cache cache.ClusterConf
+
+	// 后台刷新令牌，未配置平台时不启动
+	TokenRefresh struct {
+		Platforms []string      `json:",optional"` // 需保持令牌有效的第三方平台 AppID
+		Interval  time.Duration `json:",optional"` // 检查间隔，默认 5 分钟
+		Ahead     time.Duration `json:",optional"` // 提前刷新时长，默认 15 分钟
+	} `json:",optional"`
 }
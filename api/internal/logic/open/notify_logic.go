@@ -2,6 +2,7 @@ package logic
 
 import (
 	"context"
+	"github.com/gotid/wechat"
 	"github.com/gotid/wechat/api/internal/logic"
 	"net/http"
 
@@ -37,21 +38,8 @@ func (l *NotifyLogic) Notify(req types.PlatformReq) error {
 		return err
 	}
 
-	// 获取当前微信请求的消息管理器
-	server := wc.Server(l.writer, l.request)
-	server.Debug(true)
-
-	// 设置常规消息钩子
-	server.SetMsgHandler(logic.MsgHandler)
-
-	// 处理请求、构建响应
-	err = server.Serve()
-	if err != nil {
-		return err
-	}
-
-	// 发送响应
-	server.Send()
+	// 处理请求并发送响应
+	wechat.NewHandler(wc.Context, logic.Router).ServeHTTP(l.writer, l.request)
 
 	return nil
 }
@@ -0,0 +1,86 @@
+package logic
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gotid/wechat/api/internal/model"
+	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/pay"
+)
+
+// 支付订单状态
+const (
+	payOrderStatusUnpaid int64 = 0 // 待支付
+	payOrderStatusPaid   int64 = 1 // 成功
+)
+
+// WeappPay 返回服务商代小程序收款的微信支付控制器，小程序未绑定支付商户号时返回错误
+func WeappPay(p *pay.Pay, weapp *model.Weapp) (*pay.Pay, error) {
+	if !weapp.MchId.Valid || weapp.MchId.String == "" {
+		return nil, fmt.Errorf("小程序 %s 未绑定支付商户号", weapp.AppId)
+	}
+	return p.SubMerchant(weapp.AppId, weapp.MchId.String), nil
+}
+
+// PayOrderStatus 将微信支付交易状态映射为支付订单状态
+func PayOrderStatus(state pay.TradeState) int64 {
+	if state.Paid() {
+		return payOrderStatusPaid
+	}
+	return payOrderStatusUnpaid
+}
+
+// 退款单状态
+const (
+	payRefundStatusFailed     int64 = -1 // 失败
+	payRefundStatusProcessing int64 = 0  // 退款中
+	payRefundStatusSuccess    int64 = 1  // 成功
+)
+
+// PayRefundStatus 将微信退款状态映射为退款单状态
+func PayRefundStatus(status msg.RefundStatus) int64 {
+	switch status {
+	case msg.RefundStatusSuccess:
+		return payRefundStatusSuccess
+	case msg.RefundStatusProcessing:
+		return payRefundStatusProcessing
+	default:
+		return payRefundStatusFailed
+	}
+}
+
+// FillPayRefund 使用退款查询结果更新退款单的状态、本次退款金额和已退款金额，查询结果中无此退款单时返回 false
+func FillPayRefund(refund *model.PayRefund, outRefundNo string, result *pay.RefundQueryResult) bool {
+	record := result.Find(outRefundNo)
+	if record == nil {
+		return false
+	}
+
+	refund.TransactionId = result.TransactionID
+	refund.PayMoney = result.TotalFee
+	refund.RefundStatus = PayRefundStatus(record.RefundStatus)
+	refund.RefundMoney = record.RefundFee
+	refund.RefundTotal = result.RefundedFee(outRefundNo)
+	return true
+}
+
+// CheckPayOrder 核对支付订单与微信交易账单明细，不一致时返回原因
+func CheckPayOrder(order *model.PayOrder, record *pay.BillRecord) error {
+	if record.OutTradeNo != strconv.FormatInt(order.Id, 10) {
+		return fmt.Errorf("订单号不一致：订单=%d, 账单=%s", order.Id, record.OutTradeNo)
+	}
+	if status := PayOrderStatus(record.TradeState); order.Status != status {
+		return fmt.Errorf("订单 %d 状态不一致：订单=%d, 账单=%s", order.Id, order.Status, record.TradeState)
+	}
+	if record.TradeState == pay.TradeStateRefund {
+		return nil
+	}
+	if order.Amount.Int64 != record.TotalFee {
+		return fmt.Errorf("订单 %d 金额不一致：订单=%d, 账单=%d", order.Id, order.Amount.Int64, record.TotalFee)
+	}
+	if order.TransactionId.Valid && order.TransactionId.String != record.TransactionID {
+		return fmt.Errorf("订单 %d 微信订单号不一致：订单=%s, 账单=%s", order.Id, order.TransactionId.String, record.TransactionID)
+	}
+	return nil
+}
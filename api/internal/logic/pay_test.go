@@ -0,0 +1,125 @@
+package logic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gotid/god/lib/store/sqlx"
+
+	"github.com/gotid/wechat/api/internal/model"
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/pay"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeappPay(t *testing.T) {
+	p := pay.NewPay(&context.Context{AppID: "wx8888888888888888", PayMchID: "1900000100"})
+
+	sub, err := WeappPay(p, &model.Weapp{AppId: "wxd678efh567hg6999", MchId: sqlx.NullString{String: "1900000109", Valid: true}})
+	assert.Nil(t, err)
+	assert.Equal(t, "wxd678efh567hg6999", sub.SubAppID())
+	assert.Equal(t, "1900000109", sub.SubMchID())
+	assert.Equal(t, p.Context, sub.Context, "子商户共享服务商上下文")
+
+	_, err = WeappPay(p, &model.Weapp{AppId: "wxd678efh567hg6999"})
+	assert.NotNil(t, err, "未绑定支付商户号")
+}
+
+func TestPayOrderStatus(t *testing.T) {
+	tests := map[pay.TradeState]int64{
+		pay.TradeStateSuccess:    payOrderStatusPaid,
+		pay.TradeStateRefund:     payOrderStatusPaid,
+		pay.TradeStateNotPay:     payOrderStatusUnpaid,
+		pay.TradeStateClosed:     payOrderStatusUnpaid,
+		pay.TradeStateRevoked:    payOrderStatusUnpaid,
+		pay.TradeStateUserPaying: payOrderStatusUnpaid,
+		pay.TradeStatePayError:   payOrderStatusUnpaid,
+	}
+	for state, status := range tests {
+		assert.Equal(t, status, PayOrderStatus(state), state)
+	}
+}
+
+func TestPayRefundStatus(t *testing.T) {
+	assert.Equal(t, payRefundStatusSuccess, PayRefundStatus(msg.RefundStatusSuccess))
+	assert.Equal(t, payRefundStatusProcessing, PayRefundStatus(msg.RefundStatusProcessing))
+	assert.Equal(t, payRefundStatusFailed, PayRefundStatus(msg.RefundStatusChange))
+	assert.Equal(t, payRefundStatusFailed, PayRefundStatus(msg.RefundStatusClosed))
+}
+
+func TestFillPayRefund(t *testing.T) {
+	// 同一订单先后退款三次，第一笔成功、第二笔关闭、第三笔处理中
+	result := &pay.RefundQueryResult{
+		TransactionID: "4200000215201811190261405420",
+		OutTradeNo:    "71106718111915575302817",
+		TotalFee:      10000,
+		Refunds: []*pay.RefundRecord{
+			{OutRefundNo: "r1", RefundFee: 3000, RefundStatus: msg.RefundStatusSuccess},
+			{OutRefundNo: "r2", RefundFee: 2000, RefundStatus: msg.RefundStatusClosed},
+			{OutRefundNo: "r3", RefundFee: 1000, RefundStatus: msg.RefundStatusProcessing},
+		},
+	}
+
+	var refund model.PayRefund
+	assert.True(t, FillPayRefund(&refund, "r3", result))
+	assert.Equal(t, "4200000215201811190261405420", refund.TransactionId)
+	assert.Equal(t, int64(10000), refund.PayMoney)
+	assert.Equal(t, payRefundStatusProcessing, refund.RefundStatus)
+	assert.Equal(t, int64(1000), refund.RefundMoney)
+	assert.Equal(t, int64(3000), refund.RefundTotal, "已退款金额不含本笔及已关闭的退款")
+
+	assert.True(t, FillPayRefund(&refund, "r2", result))
+	assert.Equal(t, payRefundStatusFailed, refund.RefundStatus)
+	assert.Equal(t, int64(4000), refund.RefundTotal)
+
+	refund = model.PayRefund{}
+	assert.False(t, FillPayRefund(&refund, "r4", result))
+	assert.Equal(t, model.PayRefund{}, refund, "查询结果中无此退款单时不得修改")
+}
+
+func TestCheckPayOrder(t *testing.T) {
+	bill := "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\r\n" +
+		"`2014-11-10 16:33:45,`wx2421b1c4370ec43b,`10000100,`0,`1000,`1001690740201411100005734289,`1415640626,`085e9858e3ba5186aafcbaed1,`JSAPI,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`测试商品,`,`0.00000,`0.60%,`0.01,`0.00,`\r\n" +
+		"`2014-11-10 16:46:14,`wx2421b1c4370ec43b,`10000100,`0,`1000,`1002780740201411100005729794,`1415635270,`085e9858e90ca40c0b5aee463,`JSAPI,`REFUND,`OTHERS,`CNY,`0.00,`0.00,`2008450740201411110000174436,`1415635270-1,`10.25,`0.00,`ORIGINAL,`SUCCESS,`测试商品,`,`-0.06000,`0.60%,`0.00,`10.25,`\r\n" +
+		"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\r\n" +
+		"`2,`0.01,`10.25,`0.00,`-0.06000,`0.01,`10.25\r\n"
+
+	var records []*pay.BillRecord
+	br := pay.NewBillReader(strings.NewReader(bill))
+	for br.Next() {
+		records = append(records, br.Record())
+	}
+	assert.Nil(t, br.Err())
+	assert.Len(t, records, 2)
+	paid, refund := records[0], records[1]
+
+	order := &model.PayOrder{
+		Id:            1415640626,
+		Status:        payOrderStatusPaid,
+		Amount:        sqlx.NullInt64{Int64: 1, Valid: true},
+		TransactionId: sqlx.NullString{String: "1001690740201411100005734289", Valid: true},
+	}
+	assert.Nil(t, CheckPayOrder(order, paid))
+
+	// 退款记录的订单金额为 0，只核对状态
+	assert.Nil(t, CheckPayOrder(&model.PayOrder{Id: 1415635270, Status: payOrderStatusPaid}, refund))
+
+	assert.NotNil(t, CheckPayOrder(&model.PayOrder{Id: 1415640627, Status: payOrderStatusPaid}, paid), "订单号不一致")
+
+	unpaid := *order
+	unpaid.Status = payOrderStatusUnpaid
+	assert.NotNil(t, CheckPayOrder(&unpaid, paid), "状态不一致")
+
+	amount := *order
+	amount.Amount = sqlx.NullInt64{Int64: 2, Valid: true}
+	assert.NotNil(t, CheckPayOrder(&amount, paid), "金额不一致")
+
+	transaction := *order
+	transaction.TransactionId = sqlx.NullString{String: "1001690740201411100005734280", Valid: true}
+	assert.NotNil(t, CheckPayOrder(&transaction, paid), "微信订单号不一致")
+
+	// 尚未回写微信订单号的订单不核对微信订单号
+	transaction.TransactionId = sqlx.NullString{}
+	assert.Nil(t, CheckPayOrder(&transaction, paid))
+}
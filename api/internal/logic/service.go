@@ -8,6 +8,7 @@ import (
 	"github.com/gotid/wechat/cache"
 	"github.com/gotid/wechat/context"
 	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/server"
 )
 
 // GetWeChat 获取指定平台的微信控制器。
@@ -25,7 +26,8 @@ func GetWeChat(svcCtx *svc.ServiceContext, platformID string) (*wechat.WeChat, *
 		Token:          platform.Token,
 		EncodingAESKey: platform.EncodingAesKey,
 
-		Cache: cache.NewRedis(svcCtx.Cache),
+		Cache:                cache.NewRedis(svcCtx.Cache),
+		AuthorizerTokenStore: &WeappTokenStore{Model: svcCtx.WeappModel},
 	}
 
 	// 获取平台微信控制器
@@ -33,33 +35,23 @@ func GetWeChat(svcCtx *svc.ServiceContext, platformID string) (*wechat.WeChat, *
 	return wc, platform, nil
 }
 
-type msgHandler msg.Msg
-
-// MsgHandler 定义业务方默认消息钩子
-func MsgHandler(ctx *context.Context, m msg.Msg) (resp *msg.Response) {
-	resp = &msg.Response{
-		Scene: msg.ResponseSceneOpen,
-		Type:  msg.ResponseTypeString,
-	}
-
-	wc := wechat.Get(ctx)
-
-	handle := msgHandler(m)
-	switch m.InfoType {
-	case msg.InfoTypeVerifyTicket:
-		handle.CheckTicket(wc, resp)
-	}
-
-	return
-}
+// Router 定义业务方默认消息路由
+var Router = server.NewRouter().
+	OnInfo(msg.InfoTypeVerifyTicket, CheckTicket)
 
 // CheckTicket 检查验证票据是否已保存成功
-func (h *msgHandler) CheckTicket(wc *wechat.WeChat, _ *msg.Response) {
-	go func(id string) {
+func CheckTicket(ctx *context.Context, _ msg.Msg) *msg.Response {
+	wc := wechat.Get(ctx)
+	go func() {
 		_, err := wc.OpenPlatform().ComponentVerifyTicket()
 		if err != nil {
 			logx.Errorf("应取到验证票据，但是出错：%v", err)
 			return
 		}
-	}(h.AppID)
+	}()
+
+	return &msg.Response{
+		Scene: msg.ResponseSceneOpen,
+		Type:  msg.ResponseTypeString,
+	}
 }
@@ -0,0 +1,84 @@
+package logic
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gotid/wechat/api/internal/model"
+	"github.com/gotid/wechat/api/internal/svc"
+	"github.com/gotid/wechat/context"
+)
+
+// WeappAuthorizerSource 以平台下已授权的小程序作为需要保持令牌有效的授权方
+type WeappAuthorizerSource struct {
+	Model      *model.WeappModel
+	PlatformID string
+}
+
+// Authorizers 返回平台下已授权小程序的 AppID 及刷新令牌
+func (s *WeappAuthorizerSource) Authorizers() ([]context.Authorizer, error) {
+	weapps, err := s.Model.FindAuthorized(s.PlatformID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizers := make([]context.Authorizer, 0, len(weapps))
+	for _, w := range weapps {
+		authorizers = append(authorizers, context.Authorizer{AppID: w.AppId, RefreshToken: w.RefreshToken})
+	}
+	return authorizers, nil
+}
+
+// WeappTokenStore 将授权方刷新令牌保存至小程序表的 refresh_token 字段
+type WeappTokenStore struct {
+	Model *model.WeappModel
+}
+
+// RefreshToken 返回小程序表中的刷新令牌，小程序不存在时返回空字符串
+func (s *WeappTokenStore) RefreshToken(appID string) (string, error) {
+	weapp, err := s.Model.FindOneByAppId(appID)
+	if err == model.ErrNotFound {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return weapp.RefreshToken, nil
+}
+
+// SaveRefreshToken 更新小程序表中的刷新令牌
+func (s *WeappTokenStore) SaveRefreshToken(appID, refreshToken string) error {
+	return s.Model.UpdateRefreshToken(appID, refreshToken)
+}
+
+// NewTokenRefresher 返回指定平台的令牌刷新器，刷新平台令牌及其下已授权小程序的令牌
+func NewTokenRefresher(svcCtx *svc.ServiceContext, platformID string, interval, ahead time.Duration) (*context.TokenRefresher, error) {
+	wc, _, err := GetWeChat(svcCtx, platformID)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &WeappAuthorizerSource{Model: svcCtx.WeappModel, PlatformID: platformID}
+	return context.NewTokenRefresher(wc.Context, source, interval, ahead), nil
+}
+
+// StartTokenRefreshers 为配置中的各平台启动令牌刷新器，返回停止全部刷新器的函数
+func StartTokenRefreshers(svcCtx *svc.ServiceContext) (stop func(), err error) {
+	c := svcCtx.Config.TokenRefresh
+	refreshers := make([]*context.TokenRefresher, 0, len(c.Platforms))
+	stop = func() {
+		for _, r := range refreshers {
+			r.Stop()
+		}
+	}
+
+	for _, platformID := range c.Platforms {
+		r, err := NewTokenRefresher(svcCtx, platformID, c.Interval, c.Ahead)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("启动平台 %s 的令牌刷新器失败：%w", platformID, err)
+		}
+		r.Start()
+		refreshers = append(refreshers, r)
+	}
+	return stop, nil
+}
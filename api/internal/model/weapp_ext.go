@@ -0,0 +1,45 @@
+package model
+
+import (
+	"github.com/gotid/god/lib/g"
+	"github.com/gotid/god/lib/store/sqlx"
+)
+
+// 小程序授权失效状态
+const weappStateUnauthorized int64 = -1
+
+// FindAuthorized 查询指定开放平台下仍处于授权状态的小程序
+func (m *WeappModel) FindAuthorized(platformId string) ([]*Weapp, error) {
+	var list []*Weapp
+	query := `select ` + weappFields + ` from ` + m.table + ` where platform_id = ? and state <> ? and refresh_token <> ''`
+	if err := m.QueryNoCache(&list, query, platformId, weappStateUnauthorized); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// FindOneByAppId 按小程序 appid 查询小程序，不经缓存以读取最新的刷新令牌
+func (m *WeappModel) FindOneByAppId(appId string) (*Weapp, error) {
+	var dest Weapp
+	query := `select ` + weappFields + ` from ` + m.table + ` where app_id = ? limit 1`
+	err := m.QueryNoCache(&dest, query, appId)
+	if err == nil {
+		return &dest, nil
+	} else if err == sqlx.ErrNotFound {
+		return nil, ErrNotFound
+	} else {
+		return nil, err
+	}
+}
+
+// UpdateRefreshToken 更新小程序的授权方刷新令牌
+func (m *WeappModel) UpdateRefreshToken(appId, refreshToken string) error {
+	one, err := m.FindOneByAppId(appId)
+	if err != nil {
+		return err
+	}
+	if one.RefreshToken == refreshToken {
+		return nil
+	}
+	return m.updatePartial(g.Map{"id": one.Id, "refresh_token": refreshToken})
+}
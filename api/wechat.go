@@ -6,10 +6,12 @@ import (
 
 	"github.com/gotid/wechat/api/internal/config"
 	"github.com/gotid/wechat/api/internal/handler"
+	"github.com/gotid/wechat/api/internal/logic"
 	"github.com/gotid/wechat/api/internal/svc"
 
 	"github.com/gotid/god/api"
 	"github.com/gotid/god/lib/conf"
+	"github.com/gotid/god/lib/logx"
 )
 
 var configFile = flag.String("f", "etc/wechat-api.yaml", "配置文件")
@@ -24,6 +26,10 @@ func main() {
 	server := api.MustNewServer(c.ServerConf)
 	defer server.Stop()
 
+	stopRefreshers, err := logic.StartTokenRefreshers(ctx)
+	logx.Must(err)
+	defer stopRefreshers()
+
 	handler.RegisterHandlers(server, ctx)
 
 	fmt.Printf("Starting server at %s:%d...\n", c.Host, c.Port)
@@ -2,6 +2,7 @@
 package cache
 
 import (
+	"crypto/md5"
 	"fmt"
 	"time"
 )
@@ -11,6 +12,22 @@ const (
 	keyComponentVerifyTicket = "component_verify_ticket_%s"
 	// 开放平台令牌
 	keyComponentAccessToken = "component_access_token_%s"
+	// 开放平台令牌过期时间
+	keyComponentAccessTokenExpire = "component_access_token_expire_%s"
+	// 开放平台令牌刷新锁
+	keyComponentAccessTokenLock = "component_access_token_lock_%s"
+	// 授权方令牌
+	keyAuthorizerAccessToken = "authorizer_token_%s"
+	// 授权方令牌过期时间
+	keyAuthorizerAccessTokenExpire = "authorizer_token_expire_%s"
+	// 授权方令牌刷新锁
+	keyAuthorizerAccessTokenLock = "authorizer_token_lock_%s"
+	// 授权方刷新令牌
+	keyAuthorizerRefreshToken = "authorizer_refresh_token_%s"
+	// 微信支付平台证书
+	keyPayCertificate = "wechat_pay_certificate_%s_%s"
+	// 微信支付沙箱密钥
+	keyPaySandboxSignKey = "wechat_pay_sandbox_signkey_%s_%x"
 )
 
 type Cache interface {
@@ -33,3 +50,43 @@ func KeyComponentVerifyTicket(appID string) string {
 func KeyComponentAccessToken(appID string) string {
 	return fmt.Sprintf(keyComponentAccessToken, appID)
 }
+
+// KeyComponentAccessTokenExpire 获取开放平台令牌过期时间的缓存键
+func KeyComponentAccessTokenExpire(appID string) string {
+	return fmt.Sprintf(keyComponentAccessTokenExpire, appID)
+}
+
+// KeyComponentAccessTokenLock 获取开放平台令牌刷新锁的缓存键
+func KeyComponentAccessTokenLock(appID string) string {
+	return fmt.Sprintf(keyComponentAccessTokenLock, appID)
+}
+
+// KeyAuthorizerAccessToken 获取授权方令牌缓存键
+func KeyAuthorizerAccessToken(appID string) string {
+	return fmt.Sprintf(keyAuthorizerAccessToken, appID)
+}
+
+// KeyAuthorizerAccessTokenExpire 获取授权方令牌过期时间的缓存键
+func KeyAuthorizerAccessTokenExpire(appID string) string {
+	return fmt.Sprintf(keyAuthorizerAccessTokenExpire, appID)
+}
+
+// KeyAuthorizerAccessTokenLock 获取授权方令牌刷新锁的缓存键
+func KeyAuthorizerAccessTokenLock(appID string) string {
+	return fmt.Sprintf(keyAuthorizerAccessTokenLock, appID)
+}
+
+// KeyAuthorizerRefreshToken 获取授权方刷新令牌缓存键
+func KeyAuthorizerRefreshToken(appID string) string {
+	return fmt.Sprintf(keyAuthorizerRefreshToken, appID)
+}
+
+// KeyPayCertificate 获取微信支付平台证书缓存键
+func KeyPayCertificate(mchID, serial string) string {
+	return fmt.Sprintf(keyPayCertificate, mchID, serial)
+}
+
+// KeyPaySandboxSignKey 获取微信支付沙箱密钥缓存键，沙箱密钥由支付 key 换取，故以其摘要区分
+func KeyPaySandboxSignKey(mchID, payKey string) string {
+	return fmt.Sprintf(keyPaySandboxSignKey, mchID, md5.Sum([]byte(payKey)))
+}
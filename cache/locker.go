@@ -0,0 +1,11 @@
+package cache
+
+import "time"
+
+// Locker 支持分布式锁的缓存扩展，用于在多个实例间互斥执行刷新令牌等操作。
+type Locker interface {
+	// TryLock 尝试以 owner 身份获取锁，锁在 ttl 后自动释放，返回是否获取成功。
+	TryLock(key, owner string, ttl time.Duration) (bool, error)
+	// ReleaseLock 释放 owner 持有的锁，锁已过期或被他人持有时不做处理。
+	ReleaseLock(key, owner string) error
+}
@@ -5,20 +5,28 @@ import (
 	"time"
 )
 
-// Memory 提供一个基于内存的缓存。
+// Memory 提供一个基于内存的缓存，支持单进程内的锁。
 type Memory struct {
-	sync.Mutex
+	sync.RWMutex
 
 	data map[string]*data
 }
 
-var _ Cache = (*Memory)(nil)
+var (
+	_ Cache  = (*Memory)(nil)
+	_ Locker = (*Memory)(nil)
+)
 
 type data struct {
 	Data    interface{}
 	Expired time.Time
 }
 
+// 是否已过期
+func (d *data) expired() bool {
+	return d.Expired.Before(time.Now())
+}
+
 // NewMemory 返回一个新的内存缓存。
 func NewMemory() *Memory {
 	return &Memory{
@@ -27,11 +35,7 @@ func NewMemory() *Memory {
 }
 
 func (m *Memory) Get(key string) interface{} {
-	if v, ok := m.data[key]; ok {
-		if v.Expired.Before(time.Now()) {
-			m.deleteKey(key)
-			return nil
-		}
+	if v, ok := m.load(key); ok {
 		return v.Data
 	}
 	return nil
@@ -49,14 +53,8 @@ func (m *Memory) Set(key string, val interface{}, timeout time.Duration) error {
 }
 
 func (m *Memory) Exists(key string) bool {
-	if v, ok := m.data[key]; ok {
-		if v.Expired.Before(time.Now()) {
-			m.deleteKey(key)
-			return false
-		}
-		return true
-	}
-	return false
+	_, ok := m.load(key)
+	return ok
 }
 
 func (m *Memory) Delete(key string) error {
@@ -64,6 +62,55 @@ func (m *Memory) Delete(key string) error {
 	return nil
 }
 
+func (m *Memory) TryLock(key, owner string, ttl time.Duration) (bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	if v, ok := m.data[key]; ok && !v.expired() {
+		return false, nil
+	}
+	m.data[key] = &data{
+		Data:    owner,
+		Expired: time.Now().Add(ttl),
+	}
+	return true, nil
+}
+
+func (m *Memory) ReleaseLock(key, owner string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if v, ok := m.data[key]; ok && v.Data == owner {
+		delete(m.data, key)
+	}
+	return nil
+}
+
+// 读取未过期的键值，已过期的键值会被顺带删除
+func (m *Memory) load(key string) (*data, bool) {
+	m.RLock()
+	v, ok := m.data[key]
+	m.RUnlock()
+	if !ok {
+		return nil, false
+	}
+
+	if v.expired() {
+		m.deleteExpired(key, v)
+		return nil, false
+	}
+	return v, true
+}
+
+// 删除已过期的键值，释放读锁期间键值可能已被重新设置，仅当仍为同一过期键值时删除
+func (m *Memory) deleteExpired(key string, expired *data) {
+	m.Lock()
+	defer m.Unlock()
+	if v, ok := m.data[key]; ok && v == expired && v.expired() {
+		delete(m.data, key)
+	}
+}
+
 func (m *Memory) deleteKey(key string) {
 	m.Lock()
 	defer m.Unlock()
@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemory_Set(t *testing.T) {
+	m := NewMemory()
+	assert.Nil(t, m.Set("ticket", "ticket@@@", time.Hour))
+	assert.Nil(t, m.Set("token", "token", time.Millisecond))
+	time.Sleep(2 * time.Millisecond)
+
+	assert.Equal(t, "ticket@@@", m.Get("ticket"))
+	assert.False(t, m.Exists("token"))
+}
+
+func TestMemory_Lock(t *testing.T) {
+	m := NewMemory()
+	locked, err := m.TryLock("lock", "a", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, locked)
+
+	locked, _ = m.TryLock("lock", "b", time.Minute)
+	assert.False(t, locked, "锁被持有时无法获取")
+
+	assert.Nil(t, m.ReleaseLock("lock", "b"))
+	locked, _ = m.TryLock("lock", "b", time.Minute)
+	assert.False(t, locked, "不能释放他人持有的锁")
+
+	assert.Nil(t, m.ReleaseLock("lock", "a"))
+	locked, _ = m.TryLock("lock", "b", time.Millisecond)
+	assert.True(t, locked)
+
+	time.Sleep(2 * time.Millisecond)
+	locked, _ = m.TryLock("lock", "c", time.Minute)
+	assert.True(t, locked, "锁过期后可重新获取")
+}
+
+func TestMemory_DeleteExpiredKeepsNewValue(t *testing.T) {
+	m := NewMemory()
+	assert.Nil(t, m.Set("token", "stale", time.Millisecond))
+	time.Sleep(2 * time.Millisecond)
+	stale := m.data["token"]
+
+	// 读取到过期值后、删除前，其他协程设置了新值
+	assert.Nil(t, m.Set("token", "fresh", time.Hour))
+	m.deleteExpired("token", stale)
+	assert.Equal(t, "fresh", m.Get("token"))
+}
@@ -7,7 +7,14 @@ import (
 	"github.com/gotid/god/lib/store/kv"
 )
 
-// Redis 提供一个基于 Redis 的缓存。
+// 仅当锁仍由 owner 持有时释放，避免误删锁过期后他人获取的锁
+const unlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+else
+	return 0
+end`
+
+// Redis 提供一个基于 Redis 的缓存，支持分布式锁。
 type Redis struct {
 	store kv.Store
 }
@@ -19,7 +26,10 @@ func NewRedis(store kv.Store) *Redis {
 	}
 }
 
-var _ Cache = (*Redis)(nil)
+var (
+	_ Cache  = (*Redis)(nil)
+	_ Locker = (*Redis)(nil)
+)
 
 func (r *Redis) Get(key string) interface{} {
 	v, err := r.store.Get(key)
@@ -52,3 +62,20 @@ func (r *Redis) Delete(key string) error {
 	}
 	return nil
 }
+
+func (r *Redis) TryLock(key, owner string, ttl time.Duration) (bool, error) {
+	return r.store.SetNXEx(key, owner, seconds(ttl))
+}
+
+func (r *Redis) ReleaseLock(key, owner string) error {
+	_, err := r.store.Eval(unlockScript, key, owner)
+	return err
+}
+
+// 将时长转换为 Redis 过期秒数，不足一秒按一秒计
+func seconds(d time.Duration) int {
+	if d <= 0 {
+		return 1
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
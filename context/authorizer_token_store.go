@@ -0,0 +1,88 @@
+package context
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gotid/god/lib/gconv"
+	"github.com/gotid/god/lib/logx"
+	"github.com/gotid/wechat/cache"
+)
+
+// AuthorizerTokenStore 授权方刷新令牌存储。
+// 微信在授权和每次刷新令牌时可能返回新的刷新令牌，须持久化保存，否则授权将失效。
+type AuthorizerTokenStore interface {
+	// RefreshToken 返回授权方的刷新令牌，不存在时返回空字符串
+	RefreshToken(appID string) (string, error)
+	// SaveRefreshToken 保存授权方的刷新令牌
+	SaveRefreshToken(appID, refreshToken string) error
+}
+
+// 缓存中授权方刷新令牌的有效期，每次保存时延长
+const authorizerRefreshTokenTimeout = 30 * 24 * time.Hour
+
+// RefreshTokenSaveError 微信返回的授权方刷新令牌保存失败。
+// 旧刷新令牌此时可能已失效，调用方须另行保存 RefreshToken，否则授权将失效；本次获得的访问令牌仍可使用。
+type RefreshTokenSaveError struct {
+	AppID        string // 授权方 AppID
+	RefreshToken string // 未能保存的刷新令牌
+	Err          error  // 存储返回的错误
+}
+
+func (e *RefreshTokenSaveError) Error() string {
+	return fmt.Sprintf("保存授权方 %s 的刷新令牌失败，授权可能失效：%v", e.AppID, e.Err)
+}
+
+func (e *RefreshTokenSaveError) Unwrap() error {
+	return e.Err
+}
+
+// CacheAuthorizerTokenStore 以缓存保存授权方刷新令牌
+type CacheAuthorizerTokenStore struct {
+	Cache cache.Cache
+}
+
+// RefreshToken 返回授权方的刷新令牌，不存在时返回空字符串
+func (s *CacheAuthorizerTokenStore) RefreshToken(appID string) (string, error) {
+	v := s.Cache.Get(cache.KeyAuthorizerRefreshToken(appID))
+	if v == nil {
+		return "", nil
+	}
+	return gconv.String(v), nil
+}
+
+// SaveRefreshToken 保存授权方的刷新令牌
+func (s *CacheAuthorizerTokenStore) SaveRefreshToken(appID, refreshToken string) error {
+	return s.Cache.Set(cache.KeyAuthorizerRefreshToken(appID), refreshToken, authorizerRefreshTokenTimeout)
+}
+
+// 返回授权方刷新令牌存储，未指定时使用缓存
+func (ctx *Context) authorizerTokenStore() AuthorizerTokenStore {
+	if ctx.AuthorizerTokenStore != nil {
+		return ctx.AuthorizerTokenStore
+	}
+	return &CacheAuthorizerTokenStore{Cache: ctx.Cache}
+}
+
+// AuthorizerRefreshToken 返回已保存的授权方刷新令牌，未保存时返回 fallback
+func (ctx *Context) AuthorizerRefreshToken(appID, fallback string) string {
+	token, err := ctx.authorizerTokenStore().RefreshToken(appID)
+	if err != nil {
+		logx.Errorf("读取授权方 %s 的刷新令牌失败：%v", appID, err)
+	}
+	if token == "" {
+		return fallback
+	}
+	return token
+}
+
+// 保存微信返回的授权方刷新令牌，失败时返回 *RefreshTokenSaveError
+func (ctx *Context) saveAuthorizerRefreshToken(appID, refreshToken string) error {
+	if appID == "" || refreshToken == "" {
+		return nil
+	}
+	if err := ctx.authorizerTokenStore().SaveRefreshToken(appID, refreshToken); err != nil {
+		return &RefreshTokenSaveError{AppID: appID, RefreshToken: refreshToken, Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package context
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingTokenStore struct{}
+
+func (failingTokenStore) RefreshToken(string) (string, error) {
+	return "", errors.New("db down")
+}
+
+func (failingTokenStore) SaveRefreshToken(string, string) error {
+	return errors.New("db down")
+}
+
+func TestContext_AuthorizerRefreshToken(t *testing.T) {
+	ctx := &Context{Cache: cache.NewMemory()}
+	assert.Equal(t, "refreshtoken@@@", ctx.AuthorizerRefreshToken("wxf8b4f85f3a794e77", "refreshtoken@@@"))
+
+	// 微信返回的新刷新令牌优先于调用方传入的旧令牌
+	ctx.saveAuthorizerRefreshToken("wxf8b4f85f3a794e77", "rotated@@@")
+	assert.Equal(t, "rotated@@@", ctx.AuthorizerRefreshToken("wxf8b4f85f3a794e77", "refreshtoken@@@"))
+	assert.Equal(t, "rotated@@@", ctx.AuthorizerRefreshToken("wxf8b4f85f3a794e77", ""))
+
+	// 存储出错时返回保存错误，读取时退回调用方传入的令牌
+	ctx.AuthorizerTokenStore = failingTokenStore{}
+	var saveErr *RefreshTokenSaveError
+	assert.True(t, errors.As(ctx.saveAuthorizerRefreshToken("wxf8b4f85f3a794e77", "rotated again"), &saveErr))
+	assert.Equal(t, "rotated again", saveErr.RefreshToken)
+	assert.Equal(t, "refreshtoken@@@", ctx.AuthorizerRefreshToken("wxf8b4f85f3a794e77", "refreshtoken@@@"))
+}
+
+func TestContext_RefreshAuthorizerTokenWithoutRefreshToken(t *testing.T) {
+	ctx := &Context{Cache: cache.NewMemory()}
+	_, err := ctx.RefreshAuthorizerToken("wxf8b4f85f3a794e77", "")
+	assert.NotNil(t, err)
+}
+
+// 将微信接口请求转发至本地服务
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestContext_RefreshAuthorizerTokenSaveFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"authorizer_access_token":"fresh@@@","expires_in":7200,"authorizer_refresh_token":"rotated@@@"}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	old := util.HTTPClient
+	util.HTTPClient = &http.Client{Transport: rewriteTransport{target: target}}
+	defer func() { util.HTTPClient = old }()
+
+	ctx := &Context{AppID: "wx5c1f2ab5c3b6d8e0", Cache: cache.NewMemory(), AuthorizerTokenStore: failingTokenStore{}}
+	_ = ctx.Cache.Set(cache.KeyComponentAccessToken(ctx.AppID), "component@@@", time.Hour)
+
+	// 新刷新令牌保存失败时返回错误，同时返回可用的访问令牌
+	token, err := ctx.RefreshAuthorizerToken("wxf8b4f85f3a794e77", "refreshtoken@@@")
+	var saveErr *RefreshTokenSaveError
+	assert.True(t, errors.As(err, &saveErr))
+	assert.Equal(t, "rotated@@@", saveErr.RefreshToken)
+	assert.Equal(t, "fresh@@@", token.AccessToken)
+
+	cached, err := ctx.AuthorizerAccessToken("wxf8b4f85f3a794e77")
+	assert.Nil(t, err)
+	assert.Equal(t, "fresh@@@", cached)
+
+	// 确保令牌时同样返回访问令牌和保存错误
+	_ = ctx.InvalidateAuthorizerAccessToken("wxf8b4f85f3a794e77", "fresh@@@")
+	accessToken, err := ctx.EnsureAuthorizerAccessToken("wxf8b4f85f3a794e77", "refreshtoken@@@", 0)
+	assert.True(t, errors.As(err, &saveErr))
+	assert.Equal(t, "fresh@@@", accessToken)
+}
+
+func TestContext_RefreshAuthorizerTokenPrefersStored(t *testing.T) {
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RefreshToken string `json:"authorizer_refresh_token"`
+		}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&req))
+		sent = append(sent, req.RefreshToken)
+		fmt.Fprint(w, `{"authorizer_access_token":"fresh@@@","expires_in":7200,"authorizer_refresh_token":"rotated@@@"}`)
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+	old := util.HTTPClient
+	util.HTTPClient = &http.Client{Transport: rewriteTransport{target: target}}
+	defer func() { util.HTTPClient = old }()
+
+	ctx := &Context{AppID: "wx5c1f2ab5c3b6d8e0", Cache: cache.NewMemory()}
+	_ = ctx.Cache.Set(cache.KeyComponentAccessToken(ctx.AppID), "component@@@", time.Hour)
+
+	// 未保存时使用调用方传入的令牌，此后使用微信轮换后保存的新令牌
+	_, err := ctx.RefreshAuthorizerToken("wxf8b4f85f3a794e77", "refreshtoken@@@")
+	assert.Nil(t, err)
+	_, err = ctx.RefreshAuthorizerToken("wxf8b4f85f3a794e77", "refreshtoken@@@")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"refreshtoken@@@", "rotated@@@"}, sent)
+}
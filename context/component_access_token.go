@@ -3,10 +3,10 @@ package context
 import (
 	"encoding/json"
 	"fmt"
+	"time"
+
 	"github.com/gotid/god/lib/g"
-	"github.com/gotid/wechat/cache"
 	"github.com/gotid/wechat/util"
-	"time"
 )
 
 const urlComponentAccessToken = "https://api.weixin.qq.com/cgi-bin/component/api_component_token"
@@ -34,44 +34,56 @@ func (ctx *Context) SetComponentAccessToken(verifyTicket string) (*ComponentAcce
 		return nil, err
 	}
 
-	if token.ErrCode != 0 {
-		return nil, fmt.Errorf("SetComponentAccessToken 错误，"+
-			"errcode=%d, errmsg=%s", token.ErrCode, token.ErrMsg)
+	if err = token.WechatError.Err("SetComponentAccessToken"); err != nil {
+		return nil, err
 	}
 
-	key := cache.KeyComponentAccessToken(ctx.AppID)
 	timeout := time.Duration(token.ExpiresIn-1500) * time.Second
-	err = ctx.Cache.Set(key, token.AccessToken, timeout)
+	err = ctx.saveToken(componentTokenKeys(ctx.AppID), token.AccessToken, timeout)
 	if err != nil {
-		return nil, fmt.Errorf("SetComponentAccessToken 错误：%v", err)
+		return nil, fmt.Errorf("SetComponentAccessToken 错误：%w", err)
 	}
 
 	return token, nil
 }
 
-// ComponentAccessToken 从缓存中获取第三方平台访问令牌。
-func (ctx *Context) ComponentAccessToken() (token string, err error) {
-	key := cache.KeyComponentAccessToken(ctx.AppID)
-	val := ctx.Cache.Get(key)
-	if v, ok := val.(string); ok {
-		token = v
-	}
+// ComponentAccessToken 从缓存中获取第三方平台访问令牌，缓存未命中时刷新令牌。
+// 同一进程内的并发刷新合并为一次；缓存支持分布式锁时，集群内仅一个实例刷新，其余实例等待新令牌。
+func (ctx *Context) ComponentAccessToken() (string, error) {
+	return ctx.EnsureComponentAccessToken(0)
+}
 
-	if token == "" {
-		ticket, err := ctx.ComponentVerifyTicket()
-		if err != nil {
-			return "", err
-		}
-		at, err := ctx.SetComponentAccessToken(ticket)
-		if err != nil {
-			return "", err
-		}
-		token = at.AccessToken
+// EnsureComponentAccessToken 返回剩余有效期不少于 ahead 的第三方平台访问令牌，否则刷新令牌。
+func (ctx *Context) EnsureComponentAccessToken(ahead time.Duration) (string, error) {
+	token, err := ctx.ensureToken(componentTokenKeys(ctx.AppID), ahead, ctx.fetchComponentAccessToken)
+	if err != nil {
+		return "", err
 	}
-
 	if token == "" {
 		return "", fmt.Errorf("平台令牌初始化中，请10分钟后再试")
 	}
-
 	return token, nil
 }
+
+// ComponentAccessTokenExpiry 返回缓存的第三方平台访问令牌过期时间，未知时返回零值
+func (ctx *Context) ComponentAccessTokenExpiry() time.Time {
+	return ctx.tokenExpiry(componentTokenKeys(ctx.AppID))
+}
+
+// InvalidateComponentAccessToken 删除被微信判定无效的第三方平台访问令牌，下次获取时重新刷新
+func (ctx *Context) InvalidateComponentAccessToken(token string) error {
+	return ctx.invalidateToken(componentTokenKeys(ctx.AppID), token)
+}
+
+// 以第三方平台票据获取新的访问令牌
+func (ctx *Context) fetchComponentAccessToken() (string, error) {
+	ticket, err := ctx.ComponentVerifyTicket()
+	if err != nil {
+		return "", err
+	}
+	at, err := ctx.SetComponentAccessToken(ticket)
+	if err != nil {
+		return "", err
+	}
+	return at.AccessToken, nil
+}
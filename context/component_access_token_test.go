@@ -0,0 +1,48 @@
+package context
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext_ComponentAccessTokenWaitsForLockHolder(t *testing.T) {
+	ctx := &Context{AppID: "wx5c1f2ab5c3b6d8e0", Cache: cache.NewMemory()}
+
+	// 模拟其他实例持锁刷新，本实例未收到票据，只能等待新令牌
+	m := ctx.Cache.(*cache.Memory)
+	locked, err := m.TryLock(cache.KeyComponentAccessTokenLock(ctx.AppID), "other", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, locked)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = ctx.Cache.Set(cache.KeyComponentAccessToken(ctx.AppID), "token@@@", time.Hour)
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := ctx.ComponentAccessToken()
+			assert.Nil(t, err)
+			assert.Equal(t, "token@@@", token)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestContext_ComponentAccessTokenLockTimeout(t *testing.T) {
+	old := tokenLockTTL
+	tokenLockTTL = 50 * time.Millisecond
+	defer func() { tokenLockTTL = old }()
+
+	ctx := &Context{AppID: "wx5c1f2ab5c3b6d8e1", Cache: cache.NewMemory()}
+	_, _ = ctx.Cache.(*cache.Memory).TryLock(cache.KeyComponentAccessTokenLock(ctx.AppID), "other", time.Minute)
+
+	_, err := ctx.ComponentAccessToken()
+	assert.NotNil(t, err, "持锁实例迟迟未写入令牌时返回错误")
+}
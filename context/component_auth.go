@@ -57,11 +57,15 @@ func (ctx *Context) PreAuthCode() (string, error) {
 	}
 
 	var ret struct {
+		util.WechatError
 		PreAuthCode string `json:"pre_auth_code"`
 	}
 	if err = json.Unmarshal(data, &ret); err != nil {
 		return "", err
 	}
+	if err = ret.Err("PreAuthCode"); err != nil {
+		return "", err
+	}
 
 	return ret.PreAuthCode, nil
 }
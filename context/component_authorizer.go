@@ -13,6 +13,9 @@ const (
 	urlQueryAuth       = "https://api.weixin.qq.com/cgi-bin/component/api_query_auth?component_access_token=%s"
 	urlAuthorizerToken = "https://api.weixin.qq.com/cgi-bin/component/api_authorizer_token?component_access_token=%s"
 	urlAuthorizerInfo  = "https://api.weixin.qq.com/cgi-bin/component/api_get_authorizer_info?component_access_token=%s"
+
+	// 授权方令牌的缓存时长，早于微信的 2 小时有效期
+	authorizerTokenTimeout = 80 * time.Minute
 )
 
 type (
@@ -77,7 +80,8 @@ type (
 	}
 )
 
-// QueryAuth 使用授权码获取授权信息
+// QueryAuth 使用授权码获取授权信息，并保存授权方的访问令牌和刷新令牌。
+// 刷新令牌保存失败时同时返回授权信息和 *RefreshTokenSaveError。
 func (ctx *Context) QueryAuth(authCode string) (*AuthorizationInfo, error) {
 	accessToken, err := ctx.ComponentAccessToken()
 	if err != nil {
@@ -100,16 +104,37 @@ func (ctx *Context) QueryAuth(authCode string) (*AuthorizationInfo, error) {
 		return nil, err
 	}
 
-	if ret.ErrCode != 0 {
-		return nil, fmt.Errorf("QueryAuth 错误："+
-			"errcode=%d, errmsg=%s", ret.ErrCode, ret.ErrMsg)
+	if err = ret.Err("QueryAuth"); err != nil {
+		return nil, err
+	}
+
+	info := ret.AuthInfo
+	if info == nil {
+		return nil, nil
+	}
+	if info.AccessToken != "" {
+		expiresIn := time.Duration(info.ExpiresIn) * time.Second
+		if expiresIn <= 0 || expiresIn > authorizerTokenTimeout {
+			expiresIn = authorizerTokenTimeout
+		}
+		if err = ctx.saveToken(authorizerTokenKeys(info.AppID), info.AccessToken, expiresIn); err != nil {
+			return nil, err
+		}
 	}
 
-	return ret.AuthInfo, nil
+	// 刷新令牌保存失败时仍返回授权信息，由调用方另行保存
+	return info, ctx.saveAuthorizerRefreshToken(info.AppID, info.RefreshToken)
 }
 
-// RefreshAuthorizerToken 刷新授权方接口的调用令牌
+// RefreshAuthorizerToken 刷新授权方接口的调用令牌，并保存微信返回的新刷新令牌。
+// 优先使用已保存的刷新令牌，refreshToken 仅在未保存时使用，以免传入的旧令牌覆盖微信轮换后的新令牌；
+// 新刷新令牌保存失败时同时返回令牌和 *RefreshTokenSaveError。
 func (ctx *Context) RefreshAuthorizerToken(appID, refreshToken string) (*AuthorizerToken, error) {
+	refreshToken = ctx.AuthorizerRefreshToken(appID, refreshToken)
+	if refreshToken == "" {
+		return nil, fmt.Errorf("授权方 %s 缺少刷新令牌", appID)
+	}
+
 	accessToken, err := ctx.ComponentAccessToken()
 	if err != nil {
 		return nil, err
@@ -124,27 +149,62 @@ func (ctx *Context) RefreshAuthorizerToken(appID, refreshToken string) (*Authori
 		return nil, err
 	}
 
-	ret := &AuthorizerToken{}
-	if err = json.Unmarshal(data, ret); err != nil {
+	var ret struct {
+		util.WechatError
+		AuthorizerToken
+	}
+	if err = json.Unmarshal(data, &ret); err != nil {
+		return nil, err
+	}
+
+	if err = ret.Err("RefreshAuthorizerToken"); err != nil {
 		return nil, err
 	}
+	if ret.AppID == "" {
+		ret.AppID = appID
+	}
+	if ret.RefreshToken == "" {
+		ret.RefreshToken = refreshToken
+	}
 
-	key := "authorizer_token_" + appID
-	if err = ctx.Cache.Set(key, ret.AccessToken, 80*time.Minute); err != nil {
+	if err = ctx.saveToken(authorizerTokenKeys(appID), ret.AccessToken, authorizerTokenTimeout); err != nil {
 		return nil, err
 	}
 
-	return ret, nil
+	// 刷新令牌保存失败时仍返回新的访问令牌，由调用方另行保存刷新令牌
+	return &ret.AuthorizerToken, ctx.saveAuthorizerRefreshToken(appID, ret.RefreshToken)
 }
 
 // AuthorizerAccessToken 从缓存中获取授权方的访问令牌。
 func (ctx *Context) AuthorizerAccessToken(appID string) (string, error) {
-	key := "authorizer_token_" + appID
-	val := ctx.Cache.Get(key)
-	if val == nil {
+	token := ctx.cachedToken(authorizerTokenKeys(appID), 0)
+	if token == "" {
 		return "", fmt.Errorf("无法获取授权方 %s 的令牌", appID)
 	}
-	return val.(string), nil
+	return token, nil
+}
+
+// EnsureAuthorizerAccessToken 返回剩余有效期不少于 ahead 的授权方访问令牌，否则以刷新令牌刷新。
+// 优先使用已保存的刷新令牌，refreshToken 仅在未保存时使用；并发刷新的合并及集群互斥同 ComponentAccessToken。
+// 新刷新令牌保存失败时同时返回访问令牌和 *RefreshTokenSaveError。
+func (ctx *Context) EnsureAuthorizerAccessToken(appID, refreshToken string, ahead time.Duration) (string, error) {
+	return ctx.ensureToken(authorizerTokenKeys(appID), ahead, func() (string, error) {
+		token, err := ctx.RefreshAuthorizerToken(appID, refreshToken)
+		if token == nil {
+			return "", err
+		}
+		return token.AccessToken, err
+	})
+}
+
+// AuthorizerAccessTokenExpiry 返回缓存的授权方访问令牌过期时间，未知时返回零值
+func (ctx *Context) AuthorizerAccessTokenExpiry(appID string) time.Time {
+	return ctx.tokenExpiry(authorizerTokenKeys(appID))
+}
+
+// InvalidateAuthorizerAccessToken 删除被微信判定无效的授权方访问令牌，下次获取时重新刷新
+func (ctx *Context) InvalidateAuthorizerAccessToken(appID, token string) error {
+	return ctx.invalidateToken(authorizerTokenKeys(appID), token)
 }
 
 // AuthorizerInfo 网络获取授权方的帐号基本信息。
@@ -163,12 +223,16 @@ func (ctx *Context) AuthorizerInfo(appID string) (*AuthorizerInfo, *Authorizatio
 	}
 
 	var ret struct {
+		util.WechatError
 		AuthorizerInfo    *AuthorizerInfo    `json:"authorizer_info"`
 		AuthorizationInfo *AuthorizationInfo `json:"authorization_info"`
 	}
 	if err := json.Unmarshal(data, &ret); err != nil {
 		return nil, nil, err
 	}
+	if err := ret.Err("AuthorizerInfo"); err != nil {
+		return nil, nil, err
+	}
 
 	return ret.AuthorizerInfo, ret.AuthorizationInfo, nil
 }
@@ -2,13 +2,18 @@ package context
 
 import (
 	"fmt"
+	"time"
+
 	"github.com/gotid/god/lib/logx"
 	"github.com/gotid/wechat/cache"
 )
 
+// 第三方平台票据的有效期
+const componentVerifyTicketTimeout = 12 * time.Hour
+
 // SetComponentVerifyTicket 保存每 10 分钟推送一次的第三方平台票据
 func (ctx *Context) SetComponentVerifyTicket(v string) {
-	err := ctx.Cache.Set(cache.KeyComponentVerifyTicket(ctx.AppID), v, 0)
+	err := ctx.Cache.Set(cache.KeyComponentVerifyTicket(ctx.AppID), v, componentVerifyTicketTimeout)
 	if err != nil {
 		logx.Errorf("保存开放平台票据失败：%v", err)
 	}
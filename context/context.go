@@ -1,11 +1,9 @@
 package context
 
-import (
-	"github.com/gotid/wechat/cache"
-	"net/http"
-)
+import "github.com/gotid/wechat/cache"
 
 // Context 微信上下文结构
+// 仅包含平台配置和缓存，可在并发请求间共享；单次请求的状态由 server.Server 持有。
 type Context struct {
 	// 开放平台、客服消息公用部分
 	AppID          string // 小程序/平台 APPID
@@ -14,15 +12,21 @@ type Context struct {
 	EncodingAESKey string // 消息加解密Key
 
 	// 支付商户部分
-	PayMchID     string // 商户ID
-	PayNotifyURL string // 微信支付结果通知的接口地址
-	PayKey       string // 商户后台设置的支付 key
-	P12          []byte // 商户证书文件
+	PayMchID           string // 商户ID
+	PayNotifyURL       string // 微信支付结果通知的接口地址
+	PayRefundNotifyURL string // 微信退款结果通知的接口地址
+	PayKey             string // 商户后台设置的支付 key
+	P12                []byte // 商户证书文件，密码为商户ID
+	PaySandbox         bool   // 是否使用仿真测试系统，开启后自动以沙箱密钥签名
 
-	// 微信接口请求响应
-	Writer  http.ResponseWriter
-	Request *http.Request
+	// 微信支付 APIv3 部分
+	PayAPIv3Key   string // 商户后台设置的 APIv3 密钥
+	PayPrivateKey []byte // 商户 API 私钥，PEM 格式
+	PaySerialNo   string // 商户 API 证书序列号
 
 	// 令牌等信息缓存
 	Cache cache.Cache
+
+	// 授权方刷新令牌存储，为空时保存至缓存
+	AuthorizerTokenStore AuthorizerTokenStore
 }
@@ -0,0 +1,152 @@
+package context
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gotid/god/lib/gconv"
+	"github.com/gotid/god/lib/grand"
+	"github.com/gotid/god/lib/logx"
+	"github.com/gotid/god/lib/syncx"
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/util"
+)
+
+var (
+	// 合并同一进程内并发的令牌刷新，以令牌缓存键为键
+	tokenFlight = syncx.NewSingleFlight()
+
+	// 令牌刷新锁的有效期，也是等待其他实例刷新的最长时间。
+	// 须长于刷新请求的超时时长，否则持锁实例请求未结束锁即到期，其他实例会重复刷新。
+	tokenLockTTL = util.DefaultHTTPTimeout + 10*time.Second
+	// 等待其他实例刷新时检查缓存的间隔
+	tokenLockPoll = 100 * time.Millisecond
+)
+
+// 令牌、过期时间及刷新锁的缓存键
+type tokenKeys struct {
+	token  string
+	expire string
+	lock   string
+}
+
+func componentTokenKeys(appID string) tokenKeys {
+	return tokenKeys{
+		token:  cache.KeyComponentAccessToken(appID),
+		expire: cache.KeyComponentAccessTokenExpire(appID),
+		lock:   cache.KeyComponentAccessTokenLock(appID),
+	}
+}
+
+func authorizerTokenKeys(appID string) tokenKeys {
+	return tokenKeys{
+		token:  cache.KeyAuthorizerAccessToken(appID),
+		expire: cache.KeyAuthorizerAccessTokenExpire(appID),
+		lock:   cache.KeyAuthorizerAccessTokenLock(appID),
+	}
+}
+
+// 读取缓存的令牌，剩余有效期不足 ahead 时视为未命中；ahead 为 0 时不检查过期时间
+func (ctx *Context) cachedToken(keys tokenKeys, ahead time.Duration) string {
+	token, _ := ctx.Cache.Get(keys.token).(string)
+	if token == "" || ahead <= 0 {
+		return token
+	}
+
+	if expiry := ctx.tokenExpiry(keys); time.Until(expiry) < ahead {
+		return ""
+	}
+	return token
+}
+
+// 返回缓存的令牌过期时间，未知时返回零值
+func (ctx *Context) tokenExpiry(keys tokenKeys) time.Time {
+	v := ctx.Cache.Get(keys.expire)
+	if v == nil {
+		return time.Time{}
+	}
+	if unix := gconv.Int64(v); unix > 0 {
+		return time.Unix(unix, 0)
+	}
+	return time.Time{}
+}
+
+// 删除被微信判定无效的令牌，缓存中已是其他请求刷新的新令牌时保留
+func (ctx *Context) invalidateToken(keys tokenKeys, token string) error {
+	if cached, _ := ctx.Cache.Get(keys.token).(string); cached != token {
+		return nil
+	}
+	if err := ctx.Cache.Delete(keys.token); err != nil {
+		return err
+	}
+	return ctx.Cache.Delete(keys.expire)
+}
+
+// 保存令牌及其过期时间
+func (ctx *Context) saveToken(keys tokenKeys, token string, timeout time.Duration) error {
+	if err := ctx.Cache.Set(keys.token, token, timeout); err != nil {
+		return err
+	}
+	expiry := strconv.FormatInt(time.Now().Add(timeout).Unix(), 10)
+	return ctx.Cache.Set(keys.expire, expiry, timeout)
+}
+
+// 返回剩余有效期不少于 ahead 的令牌，否则调用 fetch 刷新。
+// 同一进程内的并发刷新合并为一次；缓存支持分布式锁时，集群内仅一个实例刷新，其余实例等待新令牌。
+func (ctx *Context) ensureToken(keys tokenKeys, ahead time.Duration, fetch func() (string, error)) (string, error) {
+	if token := ctx.cachedToken(keys, ahead); token != "" {
+		return token, nil
+	}
+
+	// fetch 可能同时返回令牌和错误，如授权方刷新令牌保存失败，此时一并返回
+	v, _, err := tokenFlight.Do(keys.token, func() (interface{}, error) {
+		return ctx.refreshToken(keys, ahead, fetch)
+	})
+	token, _ := v.(string)
+	return token, err
+}
+
+// 刷新令牌，缓存支持分布式锁时仅由持锁实例刷新
+func (ctx *Context) refreshToken(keys tokenKeys, ahead time.Duration, fetch func() (string, error)) (string, error) {
+	// 等待合并期间令牌可能已被刷新
+	if token := ctx.cachedToken(keys, ahead); token != "" {
+		return token, nil
+	}
+
+	locker, ok := ctx.Cache.(cache.Locker)
+	if !ok {
+		return fetch()
+	}
+
+	owner := grand.S(16)
+	deadline := time.Now().Add(tokenLockTTL)
+	for {
+		locked, err := locker.TryLock(keys.lock, owner, tokenLockTTL)
+		if err != nil {
+			return "", fmt.Errorf("获取令牌刷新锁失败：%w", err)
+		}
+		if locked {
+			defer func() {
+				if err := locker.ReleaseLock(keys.lock, owner); err != nil {
+					logx.Errorf("释放令牌刷新锁失败：key=%s, err=%v", keys.lock, err)
+				}
+			}()
+
+			// 获得锁前其他实例可能刚完成刷新
+			if token := ctx.cachedToken(keys, ahead); token != "" {
+				return token, nil
+			}
+			return fetch()
+		}
+
+		// 等待持锁实例写入新令牌，持锁实例异常退出时锁到期后重新争抢
+		time.Sleep(tokenLockPoll)
+		if token := ctx.cachedToken(keys, ahead); token != "" {
+			return token, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("等待其他实例刷新令牌超时：key=%s", keys.token)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package context
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gotid/god/lib/logx"
+)
+
+const (
+	// 默认的检查间隔
+	defaultRefreshInterval = 5 * time.Minute
+	// 默认的提前刷新时长，须大于检查间隔以免令牌在两次检查之间过期
+	defaultRefreshAhead = 15 * time.Minute
+	// 检查间隔的最大随机抖动比例，避免多个实例同时刷新
+	refreshJitter = 0.2
+	// 并发刷新授权方令牌的协程数
+	refreshWorkers = 8
+)
+
+type (
+	// Authorizer 需要保持令牌有效的授权方
+	Authorizer struct {
+		AppID        string // 授权方 AppID
+		RefreshToken string // 授权方刷新令牌
+	}
+
+	// AuthorizerSource 授权方来源，如已授权小程序的数据表
+	AuthorizerSource interface {
+		// Authorizers 返回需要保持令牌有效的授权方
+		Authorizers() ([]Authorizer, error)
+	}
+
+	// TokenStatus 令牌的刷新状态
+	TokenStatus struct {
+		AppID     string    // 平台或授权方 AppID，平台令牌为平台 AppID
+		Component bool      // 是否为平台令牌
+		ExpiresAt time.Time // 令牌过期时间，未知时为零值
+		CheckedAt time.Time // 上次检查时间
+		Failures  int       // 连续刷新失败次数
+		LastError string    // 上次刷新失败的原因
+	}
+
+	// TokenRefresher 后台令牌刷新器：按带抖动的间隔检查平台令牌及各授权方令牌，
+	// 在过期前主动刷新，使业务请求无需承担刷新延迟和失败。
+	TokenRefresher struct {
+		ctx      *Context
+		source   AuthorizerSource
+		interval time.Duration
+		ahead    time.Duration
+
+		lock   sync.RWMutex
+		status map[string]*TokenStatus
+
+		startOnce sync.Once
+		stopOnce  sync.Once
+		stop      chan struct{}
+		done      chan struct{}
+	}
+)
+
+// NewTokenRefresher 返回一个新的令牌刷新器，source 为空时仅刷新平台令牌。
+// interval 为检查间隔，ahead 为提前刷新时长，不大于 0 时使用默认值。
+func NewTokenRefresher(ctx *Context, source AuthorizerSource, interval, ahead time.Duration) *TokenRefresher {
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	if ahead <= 0 {
+		ahead = defaultRefreshAhead
+	}
+	if ahead <= interval {
+		ahead = interval + interval/2
+	}
+
+	return &TokenRefresher{
+		ctx:      ctx,
+		source:   source,
+		interval: interval,
+		ahead:    ahead,
+		status:   make(map[string]*TokenStatus),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start 在后台开始定期刷新，立即执行首次检查，重复调用无效
+func (r *TokenRefresher) Start() {
+	r.startOnce.Do(func() {
+		go r.run()
+	})
+}
+
+// Stop 停止刷新，并等待进行中的检查完成
+func (r *TokenRefresher) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+
+	started := true
+	r.startOnce.Do(func() {
+		started = false
+		close(r.done)
+	})
+	if started {
+		<-r.done
+	}
+}
+
+// Refresh 立即检查一次平台令牌及各授权方令牌，刷新即将过期的令牌
+func (r *TokenRefresher) Refresh() {
+	_, err := r.ctx.EnsureComponentAccessToken(r.ahead)
+	r.report(r.ctx.AppID, true, r.ctx.ComponentAccessTokenExpiry(), err)
+	if err != nil {
+		// 平台令牌无效时无法刷新授权方令牌
+		return
+	}
+
+	if r.source == nil {
+		return
+	}
+	authorizers, err := r.source.Authorizers()
+	if err != nil {
+		logx.Errorf("获取需刷新令牌的授权方失败：%v", err)
+		return
+	}
+
+	// 去重后的授权方，停止时未检查的授权方仍保留原状态
+	seen := make(map[string]bool, len(authorizers))
+	list := make([]Authorizer, 0, len(authorizers))
+	for _, a := range authorizers {
+		if a.AppID == "" || a.RefreshToken == "" || seen[a.AppID] {
+			continue
+		}
+		seen[a.AppID] = true
+		list = append(list, a)
+	}
+	r.prune(seen)
+
+	tasks := make(chan Authorizer)
+	var wg sync.WaitGroup
+	for i := 0; i < refreshWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range tasks {
+				_, err := r.ctx.EnsureAuthorizerAccessToken(a.AppID, a.RefreshToken, r.ahead)
+				r.report(a.AppID, false, r.ctx.AuthorizerAccessTokenExpiry(a.AppID), err)
+			}
+		}()
+	}
+
+dispatch:
+	for _, a := range list {
+		select {
+		case tasks <- a:
+		case <-r.stop:
+			break dispatch
+		}
+	}
+	close(tasks)
+	wg.Wait()
+}
+
+// Status 返回各令牌的刷新状态，平台令牌在前
+func (r *TokenRefresher) Status() []TokenStatus {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	list := make([]TokenStatus, 0, len(r.status))
+	for _, s := range r.status {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Component != list[j].Component {
+			return list[i].Component
+		}
+		return list[i].AppID < list[j].AppID
+	})
+	return list
+}
+
+// Healthy 是否已完成检查且所有令牌均未过期
+func (r *TokenRefresher) Healthy() bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if len(r.status) == 0 {
+		return false
+	}
+	now := time.Now()
+	for _, s := range r.status {
+		if s.ExpiresAt.Before(now) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *TokenRefresher) run() {
+	defer close(r.done)
+
+	for {
+		r.Refresh()
+
+		timer := time.NewTimer(r.nextDelay())
+		select {
+		case <-r.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// 下次检查的延迟：检查间隔加上随机抖动
+func (r *TokenRefresher) nextDelay() time.Duration {
+	return r.interval + time.Duration(rand.Int63n(int64(float64(r.interval)*refreshJitter)+1))
+}
+
+// 记录令牌的检查结果
+func (r *TokenRefresher) report(appID string, component bool, expiresAt time.Time, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	s, ok := r.status[appID]
+	if !ok {
+		s = &TokenStatus{AppID: appID, Component: component}
+		r.status[appID] = s
+	}
+	s.ExpiresAt = expiresAt
+	s.CheckedAt = time.Now()
+	if err != nil {
+		s.Failures++
+		s.LastError = err.Error()
+		logx.Errorf("刷新令牌失败：appid=%s, 连续失败=%d, err=%v", appID, s.Failures, err)
+		return
+	}
+	s.Failures = 0
+	s.LastError = ""
+}
+
+// 移除已不在授权方来源中的授权方状态
+func (r *TokenRefresher) prune(authorizers map[string]bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for appID, s := range r.status {
+		if !s.Component && !authorizers[appID] {
+			delete(r.status, appID)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type authorizerSource []Authorizer
+
+func (s authorizerSource) Authorizers() ([]Authorizer, error) {
+	return s, nil
+}
+
+func TestTokenRefresher(t *testing.T) {
+	ctx := &Context{AppID: "wx5c1f2ab5c3b6d8e2", Cache: cache.NewMemory()}
+	assert.Nil(t, ctx.saveToken(componentTokenKeys(ctx.AppID), "component@@@", time.Hour))
+	assert.Nil(t, ctx.saveToken(authorizerTokenKeys("wxa1"), "authorizer@@@", time.Hour))
+
+	r := NewTokenRefresher(ctx, authorizerSource{{AppID: "wxa1", RefreshToken: "refreshtoken@@@"}}, time.Minute, 10*time.Minute)
+	assert.False(t, r.Healthy(), "尚未检查时不健康")
+
+	r.Refresh()
+	r.Start()
+	r.Stop()
+	r.Stop()
+
+	status := r.Status()
+	assert.Len(t, status, 2)
+	assert.True(t, status[0].Component)
+	assert.Equal(t, "wxa1", status[1].AppID)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), status[1].ExpiresAt, time.Second)
+	assert.True(t, r.Healthy())
+
+	token, err := ctx.AuthorizerAccessToken("wxa1")
+	assert.Nil(t, err)
+	assert.Equal(t, "authorizer@@@", token, "令牌未临近过期时不刷新")
+}
+
+func TestTokenRefresher_Failure(t *testing.T) {
+	ctx := &Context{AppID: "wx5c1f2ab5c3b6d8e3", Cache: cache.NewMemory()}
+	assert.Nil(t, ctx.saveToken(componentTokenKeys(ctx.AppID), "component@@@", 5*time.Minute))
+
+	// 平台令牌即将过期，但尚未收到票据，刷新失败
+	r := NewTokenRefresher(ctx, nil, time.Minute, 10*time.Minute)
+	r.Refresh()
+	r.Refresh()
+
+	status := r.Status()
+	assert.Len(t, status, 1)
+	assert.Equal(t, 2, status[0].Failures)
+	assert.NotEmpty(t, status[0].LastError)
+	assert.True(t, r.Healthy(), "刷新失败但令牌仍未过期")
+
+	r.Stop()
+}
@@ -0,0 +1,47 @@
+package context
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenLockTTL(t *testing.T) {
+	assert.Greater(t, int64(tokenLockTTL), int64(util.DefaultHTTPTimeout), "刷新锁须在刷新请求超时后才到期")
+}
+
+func TestContext_EnsureTokenFetchOnce(t *testing.T) {
+	// 两个实例共用同一缓存，模拟集群部署
+	c := cache.NewMemory()
+	contexts := []*Context{{Cache: c}, {Cache: c}}
+	keys := authorizerTokenKeys("wx5c1f2ab5c3b6d8e2")
+
+	var fetched int32
+	fetch := func(ctx *Context) func() (string, error) {
+		return func() (string, error) {
+			atomic.AddInt32(&fetched, 1)
+			time.Sleep(50 * time.Millisecond)
+			return "token@@@", ctx.saveToken(keys, "token@@@", time.Hour)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		ctx := contexts[i%len(contexts)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := ctx.ensureToken(keys, 0, fetch(ctx))
+			assert.Nil(t, err)
+			assert.Equal(t, "token@@@", token)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+}
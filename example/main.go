@@ -6,6 +6,7 @@ import (
 	"github.com/gotid/wechat/cache"
 	"github.com/gotid/wechat/context"
 	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/server"
 	"net/http"
 )
 
@@ -22,38 +23,23 @@ func init() {
 }
 
 func main() {
-	http.HandleFunc("/favicon.ico", favorite)
-	http.HandleFunc("/", index)
-	err := http.ListenAndServe(":8081", nil)
-	if err != nil {
-		fmt.Printf("启动服务器错误，错误=%v", err)
-	}
-}
-
-func favorite(w http.ResponseWriter, r *http.Request) {
-	fmt.Println(w, r)
-}
-
-func index(w http.ResponseWriter, r *http.Request) {
-	wc := wechat.Get(ctx)
-	server := wc.Server(w, r)
-
-	// 设置常规消息钩子
-	server.SetMsgHandler(func(ctx *context.Context, m msg.Msg) *msg.Response {
+	// 设置消息路由
+	router := server.NewRouter().OnText(func(ctx *context.Context, m msg.Msg) *msg.Response {
 		return &msg.Response{
 			Scene: msg.ResponseSceneKefu,
 			Type:  msg.ResponseTypeXML,
-			Msg:   "hello world",
+			Msg:   msg.NewText("hello world"),
 		}
 	})
 
-	// 处理请求、构建响应
-	err := server.Serve()
+	http.HandleFunc("/favicon.ico", favorite)
+	http.Handle("/", wechat.NewHandler(ctx, router))
+	err := http.ListenAndServe(":8081", nil)
 	if err != nil {
-		fmt.Println(err)
-		return
+		fmt.Printf("启动服务器错误，错误=%v", err)
 	}
+}
 
-	// 发送响应
-	server.Send()
+func favorite(w http.ResponseWriter, r *http.Request) {
+	fmt.Println(w, r)
 }
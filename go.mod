@@ -5,4 +5,5 @@ go 1.16
 require (
 	github.com/gotid/god v1.3.47
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/crypto v0.0.0-20210921155107-089bfa567519
 )
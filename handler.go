@@ -0,0 +1,116 @@
+package wechat
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gotid/god/lib/logx"
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/payv3"
+	"github.com/gotid/wechat/server"
+)
+
+// Handler 微信推送的标准 http.Handler。
+// 依次完成回显校验、签名校验、解密、消息分发、加密和回复，失败时返回对应的 http 状态码。
+type Handler struct {
+	ctx             *context.Context
+	router          *server.Router
+	payHandler      server.PayHandlerFunc
+	refundHandler   server.RefundHandlerFunc
+	payV3Handler    server.PayV3HandlerFunc
+	refundV3Handler server.RefundV3HandlerFunc
+	payV3Certs      payv3.CertificateProvider
+	middlewares     []server.Middleware
+	debug           bool
+}
+
+// NewHandler 返回一个使用指定路由器分发消息的 http.Handler。
+func NewHandler(ctx *context.Context, router *server.Router) *Handler {
+	return &Handler{
+		ctx:    ctx,
+		router: router,
+	}
+}
+
+// Use 注册消息中间件
+func (h *Handler) Use(mws ...server.Middleware) *Handler {
+	h.middlewares = append(h.middlewares, mws...)
+	return h
+}
+
+// SetPayHandler 设置支付结果通知钩子
+func (h *Handler) SetPayHandler(ph server.PayHandlerFunc) *Handler {
+	h.payHandler = ph
+	return h
+}
+
+// SetRefundHandler 设置退款结果通知钩子
+func (h *Handler) SetRefundHandler(rh server.RefundHandlerFunc) *Handler {
+	h.refundHandler = rh
+	return h
+}
+
+// SetPayV3Handler 设置微信支付 APIv3 支付结果通知钩子
+func (h *Handler) SetPayV3Handler(ph server.PayV3HandlerFunc) *Handler {
+	h.payV3Handler = ph
+	return h
+}
+
+// SetRefundV3Handler 设置微信支付 APIv3 退款结果通知钩子
+func (h *Handler) SetRefundV3Handler(rh server.RefundV3HandlerFunc) *Handler {
+	h.refundV3Handler = rh
+	return h
+}
+
+// SetPayV3Certificates 设置校验 APIv3 回调签名的平台证书，默认自动下载和更新
+func (h *Handler) SetPayV3Certificates(certs payv3.CertificateProvider) *Handler {
+	h.payV3Certs = certs
+	return h
+}
+
+// Debug 指示是否打印调试日志，不影响签名校验
+func (h *Handler) Debug(v bool) *Handler {
+	h.debug = v
+	return h
+}
+
+// ServeHTTP 处理微信推送并回复
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	s := server.NewServer(h.ctx, w, r)
+	s.Debug(h.debug)
+	if h.router != nil {
+		s.SetRouter(h.router)
+	}
+	s.SetPayHandler(h.payHandler)
+	s.SetRefundHandler(h.refundHandler)
+	s.SetPayV3Handler(h.payV3Handler)
+	s.SetRefundV3Handler(h.refundV3Handler)
+	s.SetPayV3Certificates(h.payV3Certs)
+	s.Use(h.middlewares...)
+
+	if err := s.Serve(); err != nil {
+		code := statusCode(err)
+		logx.Errorf("微信推送处理失败：appid=%s, status=%d, err=%v", h.ctx.AppID, code, err)
+		http.Error(w, http.StatusText(code), code)
+		return
+	}
+
+	s.Send()
+}
+
+// 根据微信推送处理错误返回 http 状态码
+func statusCode(err error) int {
+	switch {
+	case errors.Is(err, server.ErrInvalidSignature):
+		return http.StatusForbidden
+	case errors.Is(err, server.ErrInvalidRequest):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
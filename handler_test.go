@@ -0,0 +1,56 @@
+package wechat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/server"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_ServeHTTP(t *testing.T) {
+	ctx := &context.Context{AppID: "wx0123456789", Token: "token", Cache: cache.NewMemory()}
+	h := NewHandler(ctx, server.NewRouter())
+
+	query := url.Values{}
+	query.Set("timestamp", "1600000000")
+	query.Set("nonce", "nonce")
+	query.Set("echostr", "echo")
+	query.Set("signature", util.Signature(ctx.Token, "1600000000", "nonce"))
+
+	// 回显校验
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "echo", w.Body.String())
+
+	// 签名错误
+	query.Set("signature", "invalid")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 调试模式下同样校验签名
+	w = httptest.NewRecorder()
+	NewHandler(ctx, server.NewRouter()).Debug(true).
+		ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// 请求体无效
+	query.Del("echostr")
+	query.Set("signature", util.Signature(ctx.Token, "1600000000", "nonce"))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/?"+query.Encode(), strings.NewReader("invalid")))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 请求方法错误
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -13,9 +13,32 @@ const (
 	TypeImage      Type = "image"                     // 图片消息
 	TypeVoice      Type = "voice"                     // 音频消息
 	TypeVideo      Type = "video"                     // 视频消息
+	TypeShortVideo Type = "shortvideo"                // 小视频消息
+	TypeLocation   Type = "location"                  // 地理位置消息
+	TypeLink       Type = "link"                      // 链接消息
 	TypeMusic      Type = "music"                     // 音乐消息
 	TypeNews       Type = "news"                      // 图文消息
-	TypeTransferKf      = "transfer_customer_service" // 转发客服消息
+	TypeEvent      Type = "event"                     // 事件推送
+	TypeMiniPage   Type = "miniprogrampage"           // 小程序卡片消息
+	TypeTransferKf Type = "transfer_customer_service" // 转发客服消息
+)
+
+const (
+	EventSubscribe             EventType = "subscribe"              // 关注
+	EventUnsubscribe           EventType = "unsubscribe"            // 取消关注
+	EventScan                  EventType = "SCAN"                   // 已关注用户扫描带参数二维码
+	EventLocation              EventType = "LOCATION"               // 上报地理位置
+	EventClick                 EventType = "CLICK"                  // 点击菜单拉取消息
+	EventView                  EventType = "VIEW"                   // 点击菜单跳转链接
+	EventScanCodePush          EventType = "scancode_push"          // 扫码推事件
+	EventScanCodeWaitMsg       EventType = "scancode_waitmsg"       // 扫码推事件且弹出“消息接收中”提示框
+	EventPicSysPhoto           EventType = "pic_sysphoto"           // 弹出系统拍照发图
+	EventPicPhotoOrAlbum       EventType = "pic_photo_or_album"     // 弹出拍照或者相册发图
+	EventPicWeixin             EventType = "pic_weixin"             // 弹出微信相册发图器
+	EventLocationSelect        EventType = "location_select"        // 弹出地理位置选择器
+	EventTemplateSendJobFinish EventType = "TEMPLATESENDJOBFINISH"  // 模板消息发送完成
+	EventMassSendJobFinish     EventType = "MASSSENDJOBFINISH"      // 群发消息发送完成
+	EventUserEnterTempSession  EventType = "user_enter_tempsession" // 用户进入小程序客服会话
 )
 
 const (
@@ -29,15 +52,71 @@ type Msg struct {
 	Base
 
 	// === 第三方平台相关 ===
-	InfoType                     InfoType `xml:"InfoType"`                     // 平台事件类型
-	AppID                        string   `xml:"AppId"`                        // 平台 AppID
-	ComponentVerifyTicket        string   `xml:"ComponentVerifyTicket"`        // 微信推送的平台票据
-	PreAuthCode                  string   `xml:"PreAuthCode"`                  // 预授权码
-	AuthorizerAppid              string   `xml:"AuthorizerAppid"`              // 授权者 AppID
-	AuthorizationCode            string   `xml:"AuthorizationCode"`            // 授权码
-	AuthorizationCodeExpiredTime int64    `xml:"AuthorizationCodeExpiredTime"` // 授权码过期时间
-	Reason                       string   `xml:"Reason"`
-	ScreenShot                   string   `xml:"ScreenShot"`
+	InfoType                     InfoType `xml:"InfoType" json:"InfoType"`                                         // 平台事件类型
+	AppID                        string   `xml:"AppId" json:"AppId"`                                               // 平台 AppID
+	ComponentVerifyTicket        string   `xml:"ComponentVerifyTicket" json:"ComponentVerifyTicket"`               // 微信推送的平台票据
+	PreAuthCode                  string   `xml:"PreAuthCode" json:"PreAuthCode"`                                   // 预授权码
+	AuthorizerAppid              string   `xml:"AuthorizerAppid" json:"AuthorizerAppid"`                           // 授权者 AppID
+	AuthorizationCode            string   `xml:"AuthorizationCode" json:"AuthorizationCode"`                       // 授权码
+	AuthorizationCodeExpiredTime int64    `xml:"AuthorizationCodeExpiredTime" json:"AuthorizationCodeExpiredTime"` // 授权码过期时间
+	Reason                       string   `xml:"Reason" json:"Reason"`
+	ScreenShot                   string   `xml:"ScreenShot" json:"ScreenShot"`
+
+	// === 普通消息相关 ===
+	MsgID        int64   `xml:"MsgId" json:"MsgId"`               // 消息 id
+	Content      string  `xml:"Content" json:"Content"`           // 文本消息内容
+	PicURL       string  `xml:"PicUrl" json:"PicUrl"`             // 图片链接
+	MediaID      string  `xml:"MediaId" json:"MediaId"`           // 图片、语音、视频消息媒体 id
+	Format       string  `xml:"Format" json:"Format"`             // 语音格式，如 amr，speex 等
+	Recognition  string  `xml:"Recognition" json:"Recognition"`   // 语音识别结果
+	ThumbMediaID string  `xml:"ThumbMediaId" json:"ThumbMediaId"` // 视频消息缩略图的媒体 id
+	LocationX    float64 `xml:"Location_X" json:"Location_X"`     // 地理位置纬度
+	LocationY    float64 `xml:"Location_Y" json:"Location_Y"`     // 地理位置经度
+	Scale        float64 `xml:"Scale" json:"Scale"`               // 地图缩放大小
+	Label        string  `xml:"Label" json:"Label"`               // 地理位置信息
+	Title        string  `xml:"Title" json:"Title"`               // 链接消息标题
+	Description  string  `xml:"Description" json:"Description"`   // 链接消息描述
+	URL          string  `xml:"Url" json:"Url"`                   // 链接消息网址
+	PagePath     string  `xml:"PagePath" json:"PagePath"`         // 小程序卡片页面路径
+	ThumbURL     string  `xml:"ThumbUrl" json:"ThumbUrl"`         // 小程序卡片封面图片链接
+
+	// === 事件推送相关 ===
+	Event       EventType `xml:"Event" json:"Event"`             // 事件类型
+	EventKey    string    `xml:"EventKey" json:"EventKey"`       // 事件 KEY 值
+	Ticket      string    `xml:"Ticket" json:"Ticket"`           // 二维码的 ticket
+	Latitude    float64   `xml:"Latitude" json:"Latitude"`       // 上报地理位置纬度
+	Longitude   float64   `xml:"Longitude" json:"Longitude"`     // 上报地理位置经度
+	Precision   float64   `xml:"Precision" json:"Precision"`     // 上报地理位置精度
+	MenuID      string    `xml:"MenuId" json:"MenuId"`           // 菜单 id，个性化菜单时有值
+	SessionFrom string    `xml:"SessionFrom" json:"SessionFrom"` // 小程序客服按钮设置的会话来源
+
+	ScanCodeInfo struct {
+		ScanType   string `xml:"ScanType" json:"ScanType"`     // 扫描类型，一般是 qrcode
+		ScanResult string `xml:"ScanResult" json:"ScanResult"` // 扫描结果
+	} `xml:"ScanCodeInfo" json:"ScanCodeInfo"` // 扫码信息
+
+	SendPicsInfo struct {
+		Count   int32 `xml:"Count" json:"Count"` // 发送的图片数量
+		PicList []struct {
+			PicMd5Sum string `xml:"PicMd5Sum" json:"PicMd5Sum"` // 图片的 MD5 值
+		} `xml:"PicList>item" json:"PicList"` // 图片列表
+	} `xml:"SendPicsInfo" json:"SendPicsInfo"` // 发图信息
+
+	SendLocationInfo struct {
+		LocationX float64 `xml:"Location_X" json:"Location_X"` // 纬度
+		LocationY float64 `xml:"Location_Y" json:"Location_Y"` // 经度
+		Scale     float64 `xml:"Scale" json:"Scale"`           // 精度
+		Label     string  `xml:"Label" json:"Label"`           // 地理位置信息
+		Poiname   string  `xml:"Poiname" json:"Poiname"`       // 朋友圈 POI 的名字
+	} `xml:"SendLocationInfo" json:"SendLocationInfo"` // 地理位置选择信息
+
+	// === 模板消息、群发消息发送结果相关 ===
+	JobID       int64  `xml:"MsgID" json:"MsgID"`             // 模板消息或群发消息 id
+	Status      string `xml:"Status" json:"Status"`           // 发送状态
+	TotalCount  int64  `xml:"TotalCount" json:"TotalCount"`   // 群发的粉丝数
+	FilterCount int64  `xml:"FilterCount" json:"FilterCount"` // 过滤后准备发送的粉丝数
+	SentCount   int64  `xml:"SentCount" json:"SentCount"`     // 发送成功的粉丝数
+	ErrorCount  int64  `xml:"ErrorCount" json:"ErrorCount"`   // 发送失败的粉丝数
 }
 
 // EncryptedMsg 安全模式下的消息体。
@@ -58,11 +137,11 @@ type EncryptedResponseMsg struct {
 
 // Base 消息中通用的基础结构。
 type Base struct {
-	XMLName      xml.Name `xml:"xml"`
-	ToUserName   CDATA    `xml:"ToUserName"`
-	FromUserName CDATA    `xml:"FromUserName"`
-	CreateTime   int64    `xml:"CreateTime"`
-	MsgType      Type     `xml:"MsgType"`
+	XMLName      xml.Name `xml:"xml" json:"-"`
+	ToUserName   CDATA    `xml:"ToUserName" json:"ToUserName"`
+	FromUserName CDATA    `xml:"FromUserName" json:"FromUserName"`
+	CreateTime   int64    `xml:"CreateTime" json:"CreateTime"`
+	MsgType      Type     `xml:"MsgType" json:"MsgType"`
 }
 
 // SetToUserName set ToUserName
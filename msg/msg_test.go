@@ -0,0 +1,75 @@
+package msg
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMsg_Unmarshal(t *testing.T) {
+	var text Msg
+	err := xml.Unmarshal([]byte(`<xml>
+<ToUserName><![CDATA[toUser]]></ToUserName>
+<FromUserName><![CDATA[fromUser]]></FromUserName>
+<CreateTime>1348831860</CreateTime>
+<MsgType><![CDATA[text]]></MsgType>
+<Content><![CDATA[this is a test]]></Content>
+<MsgId>1234567890123456</MsgId>
+</xml>`), &text)
+	assert.Nil(t, err)
+	assert.Equal(t, TypeText, text.MsgType)
+	assert.Equal(t, CDATA("fromUser"), text.FromUserName)
+	assert.Equal(t, "this is a test", text.Content)
+	assert.Equal(t, int64(1234567890123456), text.MsgID)
+
+	var location Msg
+	err = xml.Unmarshal([]byte(`<xml>
+<MsgType><![CDATA[location]]></MsgType>
+<Location_X>23.134521</Location_X>
+<Location_Y>113.358803</Location_Y>
+<Scale>20</Scale>
+<Label><![CDATA[位置信息]]></Label>
+</xml>`), &location)
+	assert.Nil(t, err)
+	assert.Equal(t, TypeLocation, location.MsgType)
+	assert.Equal(t, 23.134521, location.LocationX)
+	assert.Equal(t, 113.358803, location.LocationY)
+	assert.Equal(t, "位置信息", location.Label)
+}
+
+func TestMsg_UnmarshalEvent(t *testing.T) {
+	var scan Msg
+	err := xml.Unmarshal([]byte(`<xml>
+<MsgType><![CDATA[event]]></MsgType>
+<Event><![CDATA[scancode_waitmsg]]></Event>
+<EventKey><![CDATA[6]]></EventKey>
+<ScanCodeInfo><ScanType><![CDATA[qrcode]]></ScanType><ScanResult><![CDATA[2]]></ScanResult></ScanCodeInfo>
+</xml>`), &scan)
+	assert.Nil(t, err)
+	assert.Equal(t, EventScanCodeWaitMsg, scan.Event)
+	assert.Equal(t, "qrcode", scan.ScanCodeInfo.ScanType)
+	assert.Equal(t, "2", scan.ScanCodeInfo.ScanResult)
+
+	var pics Msg
+	err = xml.Unmarshal([]byte(`<xml>
+<MsgType><![CDATA[event]]></MsgType>
+<Event><![CDATA[pic_photo_or_album]]></Event>
+<SendPicsInfo><Count>1</Count><PicList><item><PicMd5Sum><![CDATA[5a75aaca956d97be686719218f275c6b]]></PicMd5Sum></item></PicList></SendPicsInfo>
+</xml>`), &pics)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), pics.SendPicsInfo.Count)
+	assert.Equal(t, "5a75aaca956d97be686719218f275c6b", pics.SendPicsInfo.PicList[0].PicMd5Sum)
+
+	var job Msg
+	err = xml.Unmarshal([]byte(`<xml>
+<MsgType><![CDATA[event]]></MsgType>
+<Event><![CDATA[TEMPLATESENDJOBFINISH]]></Event>
+<MsgID>200163836</MsgID>
+<Status><![CDATA[success]]></Status>
+</xml>`), &job)
+	assert.Nil(t, err)
+	assert.Equal(t, EventTemplateSendJobFinish, job.Event)
+	assert.Equal(t, int64(200163836), job.JobID)
+	assert.Equal(t, "success", job.Status)
+}
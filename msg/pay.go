@@ -0,0 +1,129 @@
+package msg
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PayNotify 支付结果通知
+// https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_7&index=8
+type PayNotify struct {
+	ReturnCode string `xml:"return_code"` // 返回状态码 SUCCESS/FAIL
+	ReturnMsg  string `xml:"return_msg"`  // 返回信息
+
+	AppID      string `xml:"appid"`        // 公众账号ID
+	MchID      string `xml:"mch_id"`       // 商户号
+	SubAppID   string `xml:"sub_appid"`    // 子商户应用ID，服务商模式时返回
+	SubMchID   string `xml:"sub_mch_id"`   // 子商户号，服务商模式时返回
+	DeviceInfo string `xml:"device_info"`  // 设备号
+	NonceStr   string `xml:"nonce_str"`    // 随机字符串
+	Sign       string `xml:"sign"`         // 签名
+	SignType   string `xml:"sign_type"`    // 签名类型 MD5/HMAC-SHA256
+	ResultCode string `xml:"result_code"`  // 业务结果 SUCCESS/FAIL
+	ErrCode    string `xml:"err_code"`     // 错误代码
+	ErrCodeDes string `xml:"err_code_des"` // 错误代码描述
+
+	OpenID      string `xml:"openid"`       // 用户标识
+	IsSubscribe string `xml:"is_subscribe"` // 是否关注公众账号 Y/N
+	TradeType   string `xml:"trade_type"`   // 交易类型 JSAPI/NATIVE/APP
+	BankType    string `xml:"bank_type"`    // 付款银行
+
+	SubOpenID      string `xml:"sub_openid"`       // 用户在子商户应用下的标识，服务商模式时返回
+	SubIsSubscribe string `xml:"sub_is_subscribe"` // 是否关注子商户公众账号 Y/N，服务商模式时返回
+
+	TotalFee           int64    `xml:"total_fee"`            // 订单金额，单位分
+	SettlementTotalFee int64    `xml:"settlement_total_fee"` // 应结订单金额，单位分
+	FeeType            string   `xml:"fee_type"`             // 货币种类
+	CashFee            int64    `xml:"cash_fee"`             // 现金支付金额，单位分
+	CashFeeType        string   `xml:"cash_fee_type"`        // 现金支付货币类型
+	CouponFee          int64    `xml:"coupon_fee"`           // 总代金券金额，单位分
+	CouponCount        int      `xml:"coupon_count"`         // 代金券使用数量
+	Coupons            []Coupon `xml:"-"`                    // 代金券列表
+
+	TransactionID string `xml:"transaction_id"` // 微信支付订单号
+	OutTradeNo    string `xml:"out_trade_no"`   // 商户订单号
+	Attach        string `xml:"attach"`         // 商家数据包
+	TimeEnd       string `xml:"time_end"`       // 支付完成时间 yyyyMMddHHmmss
+}
+
+// Coupon 代金券
+type Coupon struct {
+	ID   string // 代金券ID
+	Type string // 代金券类型 CASH/NO_CASH
+	Fee  int64  // 单个代金券支付金额，单位分
+}
+
+// Success 是否支付成功
+func (n *PayNotify) Success() bool {
+	return n.ReturnCode == "SUCCESS" && n.ResultCode == "SUCCESS"
+}
+
+// ParseCoupons 从通知参数中解析带下标的代金券字段
+func ParseCoupons(params map[string]string, count int) ([]Coupon, error) {
+	coupons := make([]Coupon, 0, count)
+	for i := 0; i < count; i++ {
+		c := Coupon{
+			ID:   params[fmt.Sprintf("coupon_id_%d", i)],
+			Type: params[fmt.Sprintf("coupon_type_%d", i)],
+		}
+
+		if fee := params[fmt.Sprintf("coupon_fee_%d", i)]; fee != "" {
+			v, err := strconv.ParseInt(fee, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("代金券金额 coupon_fee_%d=%s 无效", i, fee)
+			}
+			c.Fee = v
+		}
+
+		coupons = append(coupons, c)
+	}
+
+	return coupons, nil
+}
+
+// RefundStatus 退款状态
+type RefundStatus string
+
+const (
+	RefundStatusSuccess    RefundStatus = "SUCCESS"     // 退款成功
+	RefundStatusChange     RefundStatus = "CHANGE"      // 退款异常
+	RefundStatusClosed     RefundStatus = "REFUNDCLOSE" // 退款关闭
+	RefundStatusProcessing RefundStatus = "PROCESSING"  // 退款处理中
+)
+
+// RefundNotify 退款结果通知
+// https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_16&index=10
+type RefundNotify struct {
+	ReturnCode string `xml:"return_code"` // 返回状态码 SUCCESS/FAIL
+	ReturnMsg  string `xml:"return_msg"`  // 返回信息
+	AppID      string `xml:"appid"`       // 公众账号ID
+	MchID      string `xml:"mch_id"`      // 商户号
+	SubAppID   string `xml:"sub_appid"`   // 子商户应用ID，服务商模式时返回
+	SubMchID   string `xml:"sub_mch_id"`  // 子商户号，服务商模式时返回
+	NonceStr   string `xml:"nonce_str"`   // 随机字符串
+	ReqInfo    string `xml:"req_info"`    // 加密信息
+
+	RefundResult `xml:"-"` // 解密后的退款结果
+}
+
+// RefundResult 解密后的退款结果
+type RefundResult struct {
+	TransactionID       string       `xml:"transaction_id"`        // 微信支付订单号
+	OutTradeNo          string       `xml:"out_trade_no"`          // 商户订单号
+	RefundID            string       `xml:"refund_id"`             // 微信退款单号
+	OutRefundNo         string       `xml:"out_refund_no"`         // 商户退款单号
+	TotalFee            int64        `xml:"total_fee"`             // 订单金额，单位分
+	SettlementTotalFee  int64        `xml:"settlement_total_fee"`  // 应结订单金额，单位分
+	RefundFee           int64        `xml:"refund_fee"`            // 申请退款金额，单位分
+	SettlementRefundFee int64        `xml:"settlement_refund_fee"` // 退款金额，单位分
+	RefundStatus        RefundStatus `xml:"refund_status"`         // 退款状态
+	SuccessTime         string       `xml:"success_time"`          // 退款成功时间 yyyy-MM-dd HH:mm:ss
+	RefundRecvAccout    string       `xml:"refund_recv_accout"`    // 退款入账账户
+	RefundAccount       string       `xml:"refund_account"`        // 退款资金来源
+	RefundRequestSource string       `xml:"refund_request_source"` // 退款发起来源 API/VENDOR_PLATFORM
+}
+
+// Success 是否退款成功
+func (r *RefundResult) Success() bool {
+	return r.RefundStatus == RefundStatusSuccess
+}
@@ -0,0 +1,82 @@
+package msg
+
+// 微信支付 APIv3 回调通知事件类型
+const (
+	NotifyEventTransactionSuccess = "TRANSACTION.SUCCESS" // 支付成功
+	NotifyEventRefundSuccess      = "REFUND.SUCCESS"      // 退款成功
+	NotifyEventRefundAbnormal     = "REFUND.ABNORMAL"     // 退款异常
+	NotifyEventRefundClosed       = "REFUND.CLOSED"       // 退款关闭
+)
+
+type (
+	// NotifyV3 微信支付 APIv3 回调通知的公共信息
+	// https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_5_5.shtml
+	NotifyV3 struct {
+		ID           string `json:"id"`            // 通知ID
+		CreateTime   string `json:"create_time"`   // 通知创建时间，RFC3339 格式
+		EventType    string `json:"event_type"`    // 通知类型
+		ResourceType string `json:"resource_type"` // 通知数据类型
+		Summary      string `json:"summary"`       // 回调摘要
+	}
+
+	// PayNotifyV3 微信支付 APIv3 支付结果通知，业务字段解密自通知数据
+	PayNotifyV3 struct {
+		NotifyV3 `json:"-"`
+
+		AppID          string `json:"appid"`            // 应用ID
+		MchID          string `json:"mchid"`            // 商户号
+		OutTradeNo     string `json:"out_trade_no"`     // 商户订单号
+		TransactionID  string `json:"transaction_id"`   // 微信支付订单号
+		TradeType      string `json:"trade_type"`       // 交易类型
+		TradeState     string `json:"trade_state"`      // 交易状态
+		TradeStateDesc string `json:"trade_state_desc"` // 交易状态描述
+		BankType       string `json:"bank_type"`        // 付款银行
+		Attach         string `json:"attach"`           // 附加数据
+		SuccessTime    string `json:"success_time"`     // 支付完成时间，RFC3339 格式
+		Payer          struct {
+			OpenID string `json:"openid"` // 用户标识
+		} `json:"payer"` // 支付者
+		Amount struct {
+			Total         int64  `json:"total"`          // 订单总金额，单位分
+			PayerTotal    int64  `json:"payer_total"`    // 用户支付金额，单位分
+			Currency      string `json:"currency"`       // 货币类型
+			PayerCurrency string `json:"payer_currency"` // 用户支付币种
+		} `json:"amount"` // 订单金额
+	}
+
+	// RefundNotifyV3 微信支付 APIv3 退款结果通知，业务字段解密自通知数据
+	RefundNotifyV3 struct {
+		NotifyV3 `json:"-"`
+
+		MchID               string `json:"mchid"`                 // 商户号
+		OutTradeNo          string `json:"out_trade_no"`          // 商户订单号
+		TransactionID       string `json:"transaction_id"`        // 微信支付订单号
+		OutRefundNo         string `json:"out_refund_no"`         // 商户退款单号
+		RefundID            string `json:"refund_id"`             // 微信退款单号
+		RefundStatus        string `json:"refund_status"`         // 退款状态 SUCCESS/CLOSED/ABNORMAL
+		SuccessTime         string `json:"success_time"`          // 退款成功时间，RFC3339 格式
+		UserReceivedAccount string `json:"user_received_account"` // 退款入账账户
+		Amount              struct {
+			Total       int64 `json:"total"`        // 订单金额，单位分
+			Refund      int64 `json:"refund"`       // 退款金额，单位分
+			PayerTotal  int64 `json:"payer_total"`  // 用户支付金额，单位分
+			PayerRefund int64 `json:"payer_refund"` // 用户退款金额，单位分
+		} `json:"amount"` // 金额信息
+	}
+
+	// PayNotifyV3Response 微信支付 APIv3 回调通知响应体
+	PayNotifyV3Response struct {
+		Code    string `json:"code"`              // 返回状态码 SUCCESS/FAIL
+		Message string `json:"message,omitempty"` // 返回信息
+	}
+)
+
+// Success 是否支付成功
+func (n *PayNotifyV3) Success() bool {
+	return n.TradeState == "SUCCESS"
+}
+
+// Success 是否退款成功
+func (n *RefundNotifyV3) Success() bool {
+	return n.RefundStatus == "SUCCESS"
+}
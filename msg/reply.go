@@ -0,0 +1,232 @@
+package msg
+
+import "fmt"
+
+// MaxNewsArticles 被动回复图文消息的最大条数，微信自 2018 年 10 月 12 日起限制为 1 条，
+// 多图文须改用客服消息或群发接口
+const MaxNewsArticles = 1
+
+// Reply 被动回复消息，发送前会补全收发方及时间并进行校验。
+type Reply interface {
+	SetToUserName(CDATA)
+	SetFromUserName(CDATA)
+	SetCreateTime(int64)
+	Validate() error
+}
+
+var (
+	_ Reply = (*Text)(nil)
+	_ Reply = (*Image)(nil)
+	_ Reply = (*Voice)(nil)
+	_ Reply = (*Video)(nil)
+	_ Reply = (*Music)(nil)
+	_ Reply = (*News)(nil)
+	_ Reply = (*TransferCustomerService)(nil)
+)
+
+type (
+	// Text 文本回复
+	Text struct {
+		Base
+		Content CDATA `xml:"Content" json:"Content"`
+	}
+
+	// Image 图片回复
+	Image struct {
+		Base
+		Image struct {
+			MediaID CDATA `xml:"MediaId" json:"MediaId"`
+		} `xml:"Image" json:"Image"`
+	}
+
+	// Voice 语音回复
+	Voice struct {
+		Base
+		Voice struct {
+			MediaID CDATA `xml:"MediaId" json:"MediaId"`
+		} `xml:"Voice" json:"Voice"`
+	}
+
+	// Video 视频回复
+	Video struct {
+		Base
+		Video struct {
+			MediaID     CDATA `xml:"MediaId" json:"MediaId"`
+			Title       CDATA `xml:"Title,omitempty" json:"Title,omitempty"`
+			Description CDATA `xml:"Description,omitempty" json:"Description,omitempty"`
+		} `xml:"Video" json:"Video"`
+	}
+
+	// Music 音乐回复
+	Music struct {
+		Base
+		Music struct {
+			Title        CDATA `xml:"Title,omitempty" json:"Title,omitempty"`
+			Description  CDATA `xml:"Description,omitempty" json:"Description,omitempty"`
+			MusicURL     CDATA `xml:"MusicUrl,omitempty" json:"MusicUrl,omitempty"`
+			HQMusicURL   CDATA `xml:"HQMusicUrl,omitempty" json:"HQMusicUrl,omitempty"`
+			ThumbMediaID CDATA `xml:"ThumbMediaId" json:"ThumbMediaId"`
+		} `xml:"Music" json:"Music"`
+	}
+
+	// News 图文回复
+	News struct {
+		Base
+		ArticleCount int        `xml:"ArticleCount" json:"ArticleCount"`
+		Articles     []*Article `xml:"Articles>item" json:"Articles"`
+	}
+
+	// Article 单条图文
+	Article struct {
+		Title       CDATA `xml:"Title" json:"Title"`
+		Description CDATA `xml:"Description" json:"Description"`
+		PicURL      CDATA `xml:"PicUrl" json:"PicUrl"`
+		URL         CDATA `xml:"Url" json:"Url"`
+	}
+
+	// TransferCustomerService 转发至客服回复
+	TransferCustomerService struct {
+		Base
+		TransInfo *struct {
+			KfAccount CDATA `xml:"KfAccount" json:"KfAccount"`
+		} `xml:"TransInfo,omitempty" json:"TransInfo,omitempty"`
+	}
+)
+
+// NewText 返回文本回复
+func NewText(content string) *Text {
+	m := &Text{Content: CDATA(content)}
+	m.SetMsgType(TypeText)
+	return m
+}
+
+// Validate 校验文本回复
+func (m *Text) Validate() error {
+	if m.Content == "" {
+		return fmt.Errorf("%w：文本内容不能为空", ErrInvalidReply)
+	}
+	return nil
+}
+
+// NewImage 返回图片回复
+func NewImage(mediaID string) *Image {
+	m := &Image{}
+	m.SetMsgType(TypeImage)
+	m.Image.MediaID = CDATA(mediaID)
+	return m
+}
+
+// Validate 校验图片回复
+func (m *Image) Validate() error {
+	if m.Image.MediaID == "" {
+		return fmt.Errorf("%w：图片媒体 id 不能为空", ErrInvalidReply)
+	}
+	return nil
+}
+
+// NewVoice 返回语音回复
+func NewVoice(mediaID string) *Voice {
+	m := &Voice{}
+	m.SetMsgType(TypeVoice)
+	m.Voice.MediaID = CDATA(mediaID)
+	return m
+}
+
+// Validate 校验语音回复
+func (m *Voice) Validate() error {
+	if m.Voice.MediaID == "" {
+		return fmt.Errorf("%w：语音媒体 id 不能为空", ErrInvalidReply)
+	}
+	return nil
+}
+
+// NewVideo 返回视频回复
+func NewVideo(mediaID, title, description string) *Video {
+	m := &Video{}
+	m.SetMsgType(TypeVideo)
+	m.Video.MediaID = CDATA(mediaID)
+	m.Video.Title = CDATA(title)
+	m.Video.Description = CDATA(description)
+	return m
+}
+
+// Validate 校验视频回复
+func (m *Video) Validate() error {
+	if m.Video.MediaID == "" {
+		return fmt.Errorf("%w：视频媒体 id 不能为空", ErrInvalidReply)
+	}
+	return nil
+}
+
+// NewMusic 返回音乐回复
+func NewMusic(title, description, musicURL, hqMusicURL, thumbMediaID string) *Music {
+	m := &Music{}
+	m.SetMsgType(TypeMusic)
+	m.Music.Title = CDATA(title)
+	m.Music.Description = CDATA(description)
+	m.Music.MusicURL = CDATA(musicURL)
+	m.Music.HQMusicURL = CDATA(hqMusicURL)
+	m.Music.ThumbMediaID = CDATA(thumbMediaID)
+	return m
+}
+
+// Validate 校验音乐回复
+func (m *Music) Validate() error {
+	if m.Music.ThumbMediaID == "" {
+		return fmt.Errorf("%w：音乐缩略图媒体 id 不能为空", ErrInvalidReply)
+	}
+	return nil
+}
+
+// NewNews 返回图文回复
+func NewNews(articles ...*Article) *News {
+	m := &News{
+		ArticleCount: len(articles),
+		Articles:     articles,
+	}
+	m.SetMsgType(TypeNews)
+	return m
+}
+
+// NewArticle 返回单条图文
+func NewArticle(title, description, picURL, url string) *Article {
+	return &Article{
+		Title:       CDATA(title),
+		Description: CDATA(description),
+		PicURL:      CDATA(picURL),
+		URL:         CDATA(url),
+	}
+}
+
+// Validate 校验图文回复
+func (m *News) Validate() error {
+	if len(m.Articles) == 0 {
+		return fmt.Errorf("%w：图文列表为空", ErrInvalidReply)
+	}
+	if len(m.Articles) > MaxNewsArticles {
+		return fmt.Errorf("%w：被动回复图文最多 %d 条，实际 %d 条",
+			ErrInvalidReply, MaxNewsArticles, len(m.Articles))
+	}
+	if m.ArticleCount != len(m.Articles) {
+		return fmt.Errorf("%w：图文数量 %d 与图文列表长度 %d 不符",
+			ErrInvalidReply, m.ArticleCount, len(m.Articles))
+	}
+	return nil
+}
+
+// NewTransferCustomerService 返回转发至客服回复，可指定客服帐号
+func NewTransferCustomerService(kfAccount ...string) *TransferCustomerService {
+	m := &TransferCustomerService{}
+	m.SetMsgType(TypeTransferKf)
+	if len(kfAccount) > 0 && kfAccount[0] != "" {
+		m.TransInfo = &struct {
+			KfAccount CDATA `xml:"KfAccount" json:"KfAccount"`
+		}{KfAccount: CDATA(kfAccount[0])}
+	}
+	return m
+}
+
+// Validate 校验转发至客服回复
+func (m *TransferCustomerService) Validate() error {
+	return nil
+}
@@ -0,0 +1,50 @@
+package msg
+
+import (
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewText(t *testing.T) {
+	m := NewText("你好")
+	m.SetToUserName("toUser")
+	m.SetFromUserName("fromUser")
+	m.SetCreateTime(12345678)
+
+	bs, err := xml.Marshal(m)
+	assert.Nil(t, err)
+	assert.Equal(t, "<xml><ToUserName><![CDATA[toUser]]></ToUserName>"+
+		"<FromUserName><![CDATA[fromUser]]></FromUserName>"+
+		"<CreateTime>12345678</CreateTime><MsgType>text</MsgType>"+
+		"<Content><![CDATA[你好]]></Content></xml>", string(bs))
+	assert.Nil(t, m.Validate())
+	assert.True(t, errors.Is(NewText("").Validate(), ErrInvalidReply))
+}
+
+func TestNewNews(t *testing.T) {
+	article := NewArticle("标题", "描述", "https://example.com/a.png", "https://example.com")
+	m := NewNews(article)
+	assert.Nil(t, m.Validate())
+
+	bs, err := xml.Marshal(m)
+	assert.Nil(t, err)
+	assert.Contains(t, string(bs), "<ArticleCount>1</ArticleCount><Articles><item><Title><![CDATA[标题]]></Title>")
+
+	assert.True(t, errors.Is(NewNews().Validate(), ErrInvalidReply))
+
+	// 被动回复仅允许 1 条图文
+	assert.True(t, errors.Is(NewNews(article, article).Validate(), ErrInvalidReply))
+}
+
+func TestNewTransferCustomerService(t *testing.T) {
+	bs, err := xml.Marshal(NewTransferCustomerService())
+	assert.Nil(t, err)
+	assert.NotContains(t, string(bs), "TransInfo")
+
+	bs, err = xml.Marshal(NewTransferCustomerService("test1@test"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(bs), "<TransInfo><KfAccount><![CDATA[test1@test]]></KfAccount></TransInfo>")
+}
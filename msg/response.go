@@ -2,7 +2,10 @@ package msg
 
 import "errors"
 
-var ErrUnsupportedResponse = errors.New("不支持的消息类型")
+var (
+	ErrUnsupportedResponse = errors.New("不支持的消息类型")
+	ErrInvalidReply        = errors.New("回复消息无效")
+)
 
 // ResponseType 响应类型
 type ResponseType string
@@ -31,6 +34,7 @@ type Response struct {
 
 // PayNotifyResponse 支付通知响应体
 type PayNotifyResponse struct {
-	ReturnCode string `xml:"return_code"`
-	ReturnMsg  string `xml:"return_msg"`
+	XMLName    struct{} `xml:"xml" json:"-"`
+	ReturnCode string   `xml:"return_code"`
+	ReturnMsg  string   `xml:"return_msg"`
 }
@@ -3,7 +3,6 @@ package open
 import (
 	"github.com/gotid/wechat/context"
 	"github.com/gotid/wechat/util"
-	"net/url"
 )
 
 // Open 微信开放平台控制器
@@ -16,9 +15,13 @@ func NewPlatform(ctx *context.Context) *Open {
 	return &Open{ctx}
 }
 
-// WeApp 获取指定的代小程序
+// WeApp 获取指定的代小程序，refreshToken 为空时使用已保存的刷新令牌
 func (o *Open) WeApp(appID string, refreshToken string) *WeApp {
-	if appID == "" || refreshToken == "" {
+	if appID == "" {
+		return nil
+	}
+	refreshToken = o.AuthorizerRefreshToken(appID, refreshToken)
+	if refreshToken == "" {
 		return nil
 	}
 
@@ -31,55 +34,22 @@ func (o *Open) WeApp(appID string, refreshToken string) *WeApp {
 
 // 拉取开放平台网络请求
 func (o *Open) get(rawURL string, params map[string]string) (resp []byte, err error) {
-	// 构建完整请求网址
-	uri, err := o.buildRequestURI(rawURL, params)
-	if err != nil {
-		return nil, err
-	}
-
-	// 拉取网络请求
-	resp, err = util.HTTPGet(uri)
-	return
+	return request(o, rawURL, params, util.HTTPGet)
 }
 
 // 投递开放平台网络请求
 func (o *Open) post(rawURL string, body map[string]string) (resp []byte, err error) {
-	// 构建完整请求网址
-	uri, err := o.buildRequestURI(rawURL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// 拉取网络请求
-	resp, err = util.PostJSON(uri, body)
-	return
+	return request(o, rawURL, nil, func(uri string) ([]byte, error) {
+		return util.PostJSON(uri, body)
+	})
 }
 
-// 构建带参的完整请求网址
-func (o *Open) buildRequestURI(rawURL string, params map[string]string) (fullURL string, err error) {
-	// 解析网址
-	parsedURL, err := url.Parse(rawURL)
-	if err != nil {
-		return "", err
-	}
-
-	// 从缓存获取微信推送的平台访问令牌
-	accessToken, err := o.ComponentAccessToken()
-	if err != nil {
-		return "", err
-	}
-
-	// 增加请求参数
-	query := parsedURL.Query()
-	query.Add("access_token", accessToken)
-	if params != nil {
-		for k, v := range params {
-			query.Set(k, v)
-		}
-	}
+// 返回第三方平台访问令牌
+func (o *Open) accessToken() (string, error) {
+	return o.ComponentAccessToken()
+}
 
-	// 返回完整网址
-	parsedURL.RawQuery = query.Encode()
-	fullURL = parsedURL.String()
-	return
+// 删除失效的第三方平台访问令牌
+func (o *Open) invalidateToken(token string) error {
+	return o.InvalidateComponentAccessToken(token)
 }
@@ -0,0 +1,92 @@
+package open
+
+import (
+	"encoding/json"
+	"net/url"
+
+	"github.com/gotid/wechat/util"
+)
+
+// 请求所用的访问令牌来源
+type tokenSource interface {
+	// 返回访问令牌，缓存未命中时刷新；可能同时返回访问令牌和错误
+	accessToken() (string, error)
+	// 删除被微信判定无效的访问令牌
+	invalidateToken(token string) error
+}
+
+// 以访问令牌发起请求，令牌失效（见 util.IsTokenError）时删除缓存的令牌，刷新后重放一次。
+// 响应含非 0 错误码时返回 *util.WechatError，接口名称为请求路径；刷新令牌时新的授权方刷新令牌保存失败，仍以新的访问令牌发起请求，并返回响应和 *context.RefreshTokenSaveError。
+func request(src tokenSource, rawURL string, params map[string]string, send func(uri string) ([]byte, error)) ([]byte, error) {
+	var saveErr error
+	for retried := false; ; retried = true {
+		accessToken, err := src.accessToken()
+		if accessToken == "" {
+			return nil, err
+		}
+		if err != nil {
+			saveErr = err
+		}
+
+		// 构建完整请求网址
+		uri, err := buildRequestURI(rawURL, accessToken, params)
+		if err != nil {
+			return nil, err
+		}
+
+		// 拉取网络请求
+		resp, err := send(uri)
+		if err != nil {
+			return nil, err
+		}
+		if retried || !isTokenError(resp) {
+			if err = decodeError(resp, rawURL); err != nil {
+				return nil, err
+			}
+			return resp, saveErr
+		}
+
+		if err = src.invalidateToken(accessToken); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// 构建带访问令牌及参数的完整请求网址
+func buildRequestURI(rawURL, accessToken string, params map[string]string) (string, error) {
+	// 解析网址
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	// 增加请求参数
+	query := parsedURL.Query()
+	query.Set("access_token", accessToken)
+	for k, v := range params {
+		query.Set(k, v)
+	}
+
+	// 返回完整网址
+	parsedURL.RawQuery = query.Encode()
+	return parsedURL.String(), nil
+}
+
+// 将含非 0 错误码的响应解码为 *util.WechatError，非 JSON 响应（如图片）视为成功
+func decodeError(resp []byte, rawURL string) error {
+	var e util.WechatError
+	if json.Unmarshal(resp, &e) != nil {
+		return nil
+	}
+
+	apiName := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		apiName = u.Path
+	}
+	return e.Err(apiName)
+}
+
+// 响应是否为访问令牌失效错误，非 JSON 响应（如图片）视为否
+func isTokenError(resp []byte) bool {
+	return util.IsTokenError(util.TryDecodeError(resp, ""))
+}
@@ -0,0 +1,155 @@
+package open
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testComponentAppID = "wx5c1f2ab5c3b6d8e0"
+	testAuthorizerID   = "wxf8b4f85f3a794e77"
+)
+
+// 将微信接口请求转发至本地服务
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newTestPlatform(t *testing.T, handler http.Handler) *Open {
+	server := httptest.NewServer(handler)
+	target, _ := url.Parse(server.URL)
+	old := util.HTTPClient
+	util.HTTPClient = &http.Client{Transport: rewriteTransport{target: target}}
+	t.Cleanup(func() {
+		util.HTTPClient = old
+		server.Close()
+	})
+
+	return NewPlatform(&context.Context{
+		AppID:     testComponentAppID,
+		AppSecret: "secret",
+		Cache:     cache.NewMemory(),
+	})
+}
+
+func TestWeApp_RetryOnTokenError(t *testing.T) {
+	var calls, refreshes int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cgi-bin/component/api_authorizer_token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&refreshes, 1)
+		assert.Equal(t, "component@@@", r.URL.Query().Get("component_access_token"))
+		fmt.Fprint(w, `{"authorizer_access_token":"fresh@@@","expires_in":7200,"authorizer_refresh_token":"refresh@@@"}`)
+	})
+	mux.HandleFunc("/wxa/api", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Query().Get("access_token") != "fresh@@@" {
+			fmt.Fprint(w, `{"errcode":40001,"errmsg":"invalid credential"}`)
+			return
+		}
+		assert.Equal(t, "1", r.URL.Query().Get("page"))
+		fmt.Fprint(w, `{"errcode":0,"errmsg":"ok"}`)
+	})
+
+	o := newTestPlatform(t, mux)
+	_ = o.Cache.Set(cache.KeyComponentAccessToken(testComponentAppID), "component@@@", time.Hour)
+	_ = o.Cache.Set(cache.KeyAuthorizerAccessToken(testAuthorizerID), "stale@@@", time.Hour)
+
+	resp, err := o.WeApp(testAuthorizerID, "refresh@@@").get("https://api.weixin.qq.com/wxa/api", map[string]string{"page": "1"})
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"errcode":0,"errmsg":"ok"}`, string(resp))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refreshes))
+
+	token, err := o.AuthorizerAccessToken(testAuthorizerID)
+	assert.Nil(t, err)
+	assert.Equal(t, "fresh@@@", token)
+}
+
+func TestOpen_RetryOnlyOnce(t *testing.T) {
+	var calls, refreshes int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cgi-bin/component/api_component_token", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&refreshes, 1)
+		fmt.Fprint(w, `{"component_access_token":"fresh@@@","expires_in":7200}`)
+	})
+	mux.HandleFunc("/cgi-bin/component/api", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `{"errcode":42001,"errmsg":"access_token expired"}`)
+	})
+
+	o := newTestPlatform(t, mux)
+	_ = o.Cache.Set(cache.KeyComponentVerifyTicket(testComponentAppID), "ticket@@@", time.Hour)
+	_ = o.Cache.Set(cache.KeyComponentAccessToken(testComponentAppID), "stale@@@", time.Hour)
+
+	resp, err := o.post("https://api.weixin.qq.com/cgi-bin/component/api", map[string]string{"foo": "bar"})
+	assert.Nil(t, resp)
+	assert.True(t, errors.Is(err, util.ErrAccessTokenExpired))
+	var e *util.WechatError
+	assert.True(t, errors.As(err, &e))
+	assert.Equal(t, "/cgi-bin/component/api", e.APIName)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&refreshes))
+}
+
+func TestInvalidateKeepsNewerToken(t *testing.T) {
+	o := newTestPlatform(t, http.NotFoundHandler())
+	_ = o.Cache.Set(cache.KeyComponentAccessToken(testComponentAppID), "newer@@@", time.Hour)
+
+	// 其他请求已刷新令牌时，不删除新令牌
+	assert.Nil(t, o.invalidateToken("stale@@@"))
+	token, err := o.ComponentAccessToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "newer@@@", token)
+}
+
+func TestWeApp_TypedError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/wxa/submit_audit", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"errcode":85009,"errmsg":"already has one auditing version"}`)
+	})
+
+	o := newTestPlatform(t, mux)
+	_ = o.Cache.Set(cache.KeyAuthorizerAccessToken(testAuthorizerID), "token@@@", time.Hour)
+
+	_, err := o.WeApp(testAuthorizerID, "refresh@@@").post("https://api.weixin.qq.com/wxa/submit_audit", nil)
+	assert.True(t, util.IsAuditError(err))
+	assert.Equal(t, int64(85009), util.ErrCode(err))
+}
+
+func TestOpen_NoRetryOnTicketError(t *testing.T) {
+	var calls int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/cgi-bin/component/api", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		fmt.Fprint(w, `{"errcode":61005,"errmsg":"component ticket is expired"}`)
+	})
+
+	o := newTestPlatform(t, mux)
+	_ = o.Cache.Set(cache.KeyComponentAccessToken(testComponentAppID), "component@@@", time.Hour)
+
+	// 票据失效时刷新访问令牌无济于事，不重试也不删除令牌
+	_, err := o.post("https://api.weixin.qq.com/cgi-bin/component/api", nil)
+	assert.True(t, util.IsTicketError(err))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	token, err := o.ComponentAccessToken()
+	assert.Nil(t, err)
+	assert.Equal(t, "component@@@", token)
+}
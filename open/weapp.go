@@ -3,11 +3,9 @@ package open
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/gotid/wechat/context"
 	"github.com/gotid/wechat/util"
 	"io/ioutil"
 	"net/http"
-	"net/url"
 	"strings"
 )
 
@@ -21,41 +19,32 @@ type WeApp struct {
 
 // 拉取代小程序网络请求
 func (wa *WeApp) get(rawURL string, params map[string]string) (resp []byte, err error) {
-	// 构建完整请求网址
-	uri, err := wa.buildRequestURI(rawURL, params)
-	if err != nil {
-		return nil, err
-	}
-
-	// 拉取网络请求
-	resp, err = util.HTTPGet(uri)
-	return
+	return request(wa, rawURL, params, util.HTTPGet)
 }
 
 // 拉取代小程序图片类数据
 func (wa *WeApp) getImage(rawURL string, params map[string]string) (resp []byte, err error) {
-	// 构建完整请求网址
-	uri, err := wa.buildRequestURI(rawURL, params)
-	if err != nil {
-		return nil, err
-	}
+	var contentType string
+	body, err := request(wa, rawURL, params, func(uri string) ([]byte, error) {
+		// 拉取网络请求
+		response, err := util.HTTPClient.Get(uri)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+
+		// 判断响应状态
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("WeApp.getImage失败：网址=%s，状态码=%d", uri, response.StatusCode)
+		}
 
-	// 拉取网络请求
-	response, err := http.Get(uri)
+		// 读取响应数据
+		contentType = response.Header.Get("Content-Type")
+		return ioutil.ReadAll(response.Body)
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
-
-	// 判断响应状态
-	if response.StatusCode != http.StatusOK {
-		err = fmt.Errorf("WeApp.getImage失败：网址=%s，状态码=%d", uri, response.StatusCode)
-		return
-	}
-
-	// 读取响应数据
-	body, err := ioutil.ReadAll(response.Body)
-	contentType := response.Header.Get("Content-Type")
 
 	// 根据内容类型返回响应
 	if contentType == "image/jpeg" {
@@ -64,8 +53,7 @@ func (wa *WeApp) getImage(rawURL string, params map[string]string) (resp []byte,
 		var jsonErr util.WechatError
 		err = json.Unmarshal(body, &jsonErr)
 		if err == nil && jsonErr.ErrCode != 0 {
-			err = fmt.Errorf("WeApp.getImage失败：[%d] %s", jsonErr.ErrCode, jsonErr.ErrMsg)
-			return nil, err
+			return nil, jsonErr.Err("WeApp.getImage")
 		}
 	} else {
 		err = fmt.Errorf("WeApp.getImage失败，期待 image/jpeg，实际返回：%s", contentType)
@@ -77,48 +65,17 @@ func (wa *WeApp) getImage(rawURL string, params map[string]string) (resp []byte,
 
 // 投递代小程序网络请求
 func (wa *WeApp) post(rawURL string, body map[string]string) (resp []byte, err error) {
-	// 构建完整请求网址
-	uri, err := wa.buildRequestURI(rawURL, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// 拉取网络请求
-	resp, err = util.PostJSON(uri, body)
-	return
+	return request(wa, rawURL, nil, func(uri string) ([]byte, error) {
+		return util.PostJSON(uri, body)
+	})
 }
 
-// 构建带参的完整请求网址
-func (wa *WeApp) buildRequest(rawURL string, params map[string]string) (fullURL string, err error) {
-	// 解析网址
-	parsedURL, err := url.Parse(rawURL)
-	if err != nil {
-		return "", err
-	}
-
-	// 从缓存获取授权方访问令牌
-	accessToken, err := wa.AuthorizerAccessToken(wa.AppID)
-	// 缓存获取不到，则网络获取
-	if err != nil {
-		var token *context.AuthorizerToken
-		token, err = wa.RefreshAuthorizerToken(wa.AppID, wa.RefreshToken)
-		if err != nil {
-			return
-		}
-		accessToken = token.AccessToken
-	}
-
-	// 增加请求参数
-	query := parsedURL.Query()
-	query.Add("access_token", accessToken)
-	if params != nil {
-		for k, v := range params {
-			query.Set(k, v)
-		}
-	}
+// 返回授权方访问令牌，缓存获取不到则以已保存的刷新令牌网络获取
+func (wa *WeApp) accessToken() (string, error) {
+	return wa.EnsureAuthorizerAccessToken(wa.AppID, wa.RefreshToken, 0)
+}
 
-	// 返回完整网址
-	parsedURL.RawQuery = query.Encode()
-	fullURL = parsedURL.String()
-	return
+// 删除失效的授权方访问令牌
+func (wa *WeApp) invalidateToken(token string) error {
+	return wa.InvalidateAuthorizerAccessToken(wa.AppID, token)
 }
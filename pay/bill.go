@@ -0,0 +1,119 @@
+package pay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+
+	"github.com/gotid/wechat/util"
+)
+
+const (
+	pathDownloadBill     = "/pay/downloadbill"
+	pathDownloadFundFlow = "/pay/downloadfundflow"
+)
+
+// BillType 交易账单类型
+type BillType string
+
+const (
+	BillTypeAll            BillType = "ALL"             // 当日所有订单信息（不含充值退款订单）
+	BillTypeSuccess        BillType = "SUCCESS"         // 当日成功支付的订单（不含充值退款订单）
+	BillTypeRefund         BillType = "REFUND"          // 当日退款订单（不含充值退款订单）
+	BillTypeRechargeRefund BillType = "RECHARGE_REFUND" // 当日充值退款订单
+)
+
+// AccountType 资金账户类型
+type AccountType string
+
+const (
+	AccountTypeBasic     AccountType = "Basic"     // 基本账户
+	AccountTypeOperation AccountType = "Operation" // 运营账户
+	AccountTypeFees      AccountType = "Fees"      // 手续费账户
+)
+
+// DownloadBill 下载交易账单，账单日期格式为 yyyyMMdd。
+// 返回解压后的账单数据流，可交由 NewBillReader 逐行解析，由调用方负责关闭。
+// https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_6
+func (p *Pay) DownloadBill(billDate string, billType BillType, compressed bool) (io.ReadCloser, error) {
+	params := map[string]string{
+		"bill_date": billDate,
+		"bill_type": string(billType),
+	}
+	if compressed {
+		params["tar_type"] = "GZIP"
+	}
+
+	body, err := p.postStream(pathDownloadBill, params, util.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return openBill(body, compressed)
+}
+
+// DownloadFundFlow 下载资金账单，账单日期格式为 yyyyMMdd，需要商户证书。
+// 返回解压后的账单数据流，可交由 NewFundFlowReader 逐行解析，由调用方负责关闭。
+// https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_18&index=7
+func (p *Pay) DownloadFundFlow(billDate string, accountType AccountType, compressed bool) (io.ReadCloser, error) {
+	client, err := p.tlsClient()
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"bill_date":    billDate,
+		"account_type": string(accountType),
+		"sign_type":    util.SignTypeHMACSHA256, // 资金账单仅支持 HMAC-SHA256
+	}
+	if compressed {
+		params["tar_type"] = "GZIP"
+	}
+
+	body, err := p.postStream(pathDownloadFundFlow, params, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return openBill(body, compressed)
+}
+
+// 账单数据流，读取解压后的数据，关闭原始响应
+type billBody struct {
+	io.Reader
+	io.Closer
+}
+
+// 打开账单响应：失败时微信返回 XML 格式的错误信息，成功时返回文本或 gzip 压缩的账单
+func openBill(body io.ReadCloser, compressed bool) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	if head, _ := br.Peek(5); string(head) == "<xml>" {
+		defer body.Close()
+		data, err := ioutil.ReadAll(br)
+		if err != nil {
+			return nil, err
+		}
+		m, err := util.XMLToMap(data)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &Error{
+			ReturnCode: m["return_code"],
+			ReturnMsg:  m["return_msg"],
+			ErrCode:    m["error_code"],
+			ErrCodeDes: m["return_msg"],
+		}
+	}
+
+	if !compressed {
+		return billBody{br, body}, nil
+	}
+
+	gr, err := gzip.NewReader(br)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return billBody{gr, body}, nil
+}
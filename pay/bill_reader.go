@@ -0,0 +1,326 @@
+package pay
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gotid/god/lib/gconv"
+	"github.com/gotid/wechat/msg"
+)
+
+// 账单中的时间均为北京时间
+var billLocation = time.FixedZone("CST", 8*3600)
+
+type (
+	// BillRecord 交易账单明细，金额单位为分
+	BillRecord struct {
+		TradeTime          time.Time        // 交易时间
+		AppID              string           // 公众账号ID
+		MchID              string           // 商户号
+		SubMchID           string           // 特约商户号
+		DeviceInfo         string           // 设备号
+		TransactionID      string           // 微信订单号
+		OutTradeNo         string           // 商户订单号
+		OpenID             string           // 用户标识
+		TradeType          TradeType        // 交易类型
+		TradeState         TradeState       // 交易状态
+		BankType           string           // 付款银行
+		FeeType            string           // 货币种类
+		SettlementTotalFee int64            // 应结订单金额
+		CouponFee          int64            // 代金券金额
+		RefundID           string           // 微信退款单号
+		OutRefundNo        string           // 商户退款单号
+		RefundFee          int64            // 退款金额
+		CouponRefundFee    int64            // 充值券退款金额
+		RefundType         string           // 退款类型
+		RefundStatus       msg.RefundStatus // 退款状态
+		Body               string           // 商品名称
+		Attach             string           // 商户数据包
+		ServiceCharge      int64            // 手续费
+		Rate               string           // 费率，如 0.60%
+		TotalFee           int64            // 订单金额
+		ApplyRefundFee     int64            // 申请退款金额
+		RateRemark         string           // 费率备注
+		RefundApplyTime    time.Time        // 退款申请时间，仅退款账单返回
+		RefundSuccessTime  time.Time        // 退款成功时间，仅退款账单返回
+	}
+
+	// BillSummary 交易账单汇总，金额单位为分
+	BillSummary struct {
+		TotalCount         int   // 总交易单数
+		SettlementTotalFee int64 // 应结订单总金额
+		RefundFee          int64 // 退款总金额
+		CouponRefundFee    int64 // 充值券退款总金额
+		ServiceCharge      int64 // 手续费总金额
+		TotalFee           int64 // 订单总金额
+		ApplyRefundFee     int64 // 申请退款总金额
+	}
+
+	// FundFlowRecord 资金账单明细，金额单位为分
+	FundFlowRecord struct {
+		AccountingTime time.Time // 记账时间
+		TransactionID  string    // 微信支付业务单号
+		FlowID         string    // 资金流水单号
+		BizName        string    // 业务名称
+		BizType        string    // 业务类型
+		FlowType       string    // 收支类型：收入/支出
+		Amount         int64     // 收支金额
+		Balance        int64     // 账户结余
+		Applicant      string    // 资金变更提交申请人
+		Remark         string    // 备注
+		BizVoucherID   string    // 业务凭证号
+	}
+
+	// FundFlowSummary 资金账单汇总，金额单位为分
+	FundFlowSummary struct {
+		TotalCount    int   // 资金流水总笔数
+		IncomeCount   int   // 收入笔数
+		IncomeAmount  int64 // 收入金额
+		ExpenseCount  int   // 支出笔数
+		ExpenseAmount int64 // 支出金额
+	}
+
+	// BillReader 逐行读取交易账单
+	BillReader struct {
+		r      *billReader
+		record *BillRecord
+	}
+
+	// FundFlowReader 逐行读取资金账单
+	FundFlowReader struct {
+		r      *billReader
+		record *FundFlowRecord
+	}
+)
+
+// NewBillReader 返回一个新的交易账单读取器
+func NewBillReader(r io.Reader) *BillReader {
+	return &BillReader{r: newBillReader(r)}
+}
+
+// Next 读取下一条明细，读完全部明细或出错时返回 false
+func (b *BillReader) Next() bool {
+	row, ok := b.r.next()
+	if !ok {
+		b.record = nil
+		return false
+	}
+
+	b.record = &BillRecord{
+		TradeTime:          billTime(row["交易时间"]),
+		AppID:              row["公众账号ID"],
+		MchID:              row["商户号"],
+		SubMchID:           firstOf(row, "特约商户号", "子商户号"),
+		DeviceInfo:         row["设备号"],
+		TransactionID:      row["微信订单号"],
+		OutTradeNo:         row["商户订单号"],
+		OpenID:             row["用户标识"],
+		TradeType:          TradeType(row["交易类型"]),
+		TradeState:         TradeState(row["交易状态"]),
+		BankType:           row["付款银行"],
+		FeeType:            row["货币种类"],
+		SettlementTotalFee: billFen(firstOf(row, "应结订单金额", "总金额")),
+		CouponFee:          billFen(firstOf(row, "代金券金额", "代金券或立减优惠金额")),
+		RefundID:           row["微信退款单号"],
+		OutRefundNo:        row["商户退款单号"],
+		RefundFee:          billFen(row["退款金额"]),
+		CouponRefundFee:    billFen(firstOf(row, "充值券退款金额", "代金券或立减优惠退款金额")),
+		RefundType:         row["退款类型"],
+		RefundStatus:       msg.RefundStatus(row["退款状态"]),
+		Body:               row["商品名称"],
+		Attach:             row["商户数据包"],
+		ServiceCharge:      billFen(row["手续费"]),
+		Rate:               row["费率"],
+		TotalFee:           billFen(row["订单金额"]),
+		ApplyRefundFee:     billFen(row["申请退款金额"]),
+		RateRemark:         row["费率备注"],
+		RefundApplyTime:    billTime(row["退款申请时间"]),
+		RefundSuccessTime:  billTime(row["退款成功时间"]),
+	}
+	return true
+}
+
+// Record 返回当前明细
+func (b *BillReader) Record() *BillRecord {
+	return b.record
+}
+
+// Summary 返回账单汇总，读完全部明细后可用
+func (b *BillReader) Summary() *BillSummary {
+	row := b.r.summary
+	if row == nil {
+		return nil
+	}
+
+	return &BillSummary{
+		TotalCount:         gconv.Int(row["总交易单数"]),
+		SettlementTotalFee: billFen(firstOf(row, "应结订单总金额", "总交易额")),
+		RefundFee:          billFen(row["退款总金额"]),
+		CouponRefundFee:    billFen(firstOf(row, "充值券退款总金额", "代金券或立减优惠退款总金额")),
+		ServiceCharge:      billFen(row["手续费总金额"]),
+		TotalFee:           billFen(row["订单总金额"]),
+		ApplyRefundFee:     billFen(row["申请退款总金额"]),
+	}
+}
+
+// Err 返回读取过程中的错误
+func (b *BillReader) Err() error {
+	return b.r.err
+}
+
+// NewFundFlowReader 返回一个新的资金账单读取器
+func NewFundFlowReader(r io.Reader) *FundFlowReader {
+	return &FundFlowReader{r: newBillReader(r)}
+}
+
+// Next 读取下一条明细，读完全部明细或出错时返回 false
+func (f *FundFlowReader) Next() bool {
+	row, ok := f.r.next()
+	if !ok {
+		f.record = nil
+		return false
+	}
+
+	f.record = &FundFlowRecord{
+		AccountingTime: billTime(row["记账时间"]),
+		TransactionID:  row["微信支付业务单号"],
+		FlowID:         row["资金流水单号"],
+		BizName:        row["业务名称"],
+		BizType:        row["业务类型"],
+		FlowType:       row["收支类型"],
+		Amount:         billFen(row["收支金额（元）"]),
+		Balance:        billFen(row["账户结余（元）"]),
+		Applicant:      row["资金变更提交申请人"],
+		Remark:         row["备注"],
+		BizVoucherID:   row["业务凭证号"],
+	}
+	return true
+}
+
+// Record 返回当前明细
+func (f *FundFlowReader) Record() *FundFlowRecord {
+	return f.record
+}
+
+// Summary 返回账单汇总，读完全部明细后可用
+func (f *FundFlowReader) Summary() *FundFlowSummary {
+	row := f.r.summary
+	if row == nil {
+		return nil
+	}
+
+	return &FundFlowSummary{
+		TotalCount:    gconv.Int(row["资金流水总笔数"]),
+		IncomeCount:   gconv.Int(row["收入笔数"]),
+		IncomeAmount:  billFen(row["收入金额"]),
+		ExpenseCount:  gconv.Int(row["支出笔数"]),
+		ExpenseAmount: billFen(row["支出金额"]),
+	}
+}
+
+// Err 返回读取过程中的错误
+func (f *FundFlowReader) Err() error {
+	return f.r.err
+}
+
+// 账单 CSV 读取器：首行为明细表头，随后为以反引号开头的明细，
+// 以首列非时间的汇总表头及汇总行结尾，各行按表头名称转为键值对。
+type billReader struct {
+	r       *csv.Reader
+	header  []string
+	summary map[string]string
+	err     error
+	done    bool
+}
+
+func newBillReader(r io.Reader) *billReader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	return &billReader{r: cr}
+}
+
+// 读取下一条明细，遇到汇总行或出错时结束
+func (b *billReader) next() (map[string]string, bool) {
+	for !b.done {
+		fields, err := b.r.Read()
+		if err != nil {
+			if err != io.EOF {
+				b.err = err
+			}
+			b.done = true
+			break
+		}
+		fields = trimBillFields(fields)
+
+		if b.header == nil {
+			fields[0] = strings.TrimPrefix(fields[0], "\ufeff")
+			b.header = fields
+			continue
+		}
+
+		if !isBillTime(fields[0]) {
+			b.done = true
+			if values, err := b.r.Read(); err == nil {
+				b.summary = billRow(fields, trimBillFields(values))
+			} else if err != io.EOF {
+				b.err = err
+			}
+			break
+		}
+
+		return billRow(b.header, fields), true
+	}
+
+	return nil, false
+}
+
+// 去掉字段前的反引号，微信以此防止 Excel 将单号转为科学计数法
+func trimBillFields(fields []string) []string {
+	for i, field := range fields {
+		fields[i] = strings.TrimPrefix(strings.TrimSpace(field), "`")
+	}
+	return fields
+}
+
+func billRow(header, fields []string) map[string]string {
+	row := make(map[string]string, len(header))
+	for i, name := range header {
+		if i < len(fields) {
+			row[name] = fields[i]
+		}
+	}
+	return row
+}
+
+// 明细行首列为交易或记账时间
+func isBillTime(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+func billTime(s string) time.Time {
+	t, _ := time.ParseInLocation("2006-01-02 15:04:05", s, billLocation)
+	return t
+}
+
+// 将以元为单位的金额转为分
+func billFen(s string) int64 {
+	yuan, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(math.Round(yuan * 100))
+}
+
+func firstOf(row map[string]string, names ...string) string {
+	for _, name := range names {
+		if v, ok := row[name]; ok {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+package pay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const testBill = "\ufeff交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\r\n" +
+	"`2014-11-10 16:33:45,`wx2421b1c4370ec43b,`10000100,`0,`1000,`1001690740201411100005734289,`1415640626,`085e9858e3ba5186aafcbaed1,`MICROPAY,`SUCCESS,`OTHERS,`CNY,`0.01,`0.00,`0,`0,`0.00,`0.00,`,`,`被扫支付测试,`订单额外描述,`0.00000,`0.60%,`0.01,`0.00,`\r\n" +
+	"`2014-11-10 16:46:14,`wx2421b1c4370ec43b,`10000100,`0,`1000,`1002780740201411100005729794,`1415635270,`085e9858e90ca40c0b5aee463,`MICROPAY,`REFUND,`OTHERS,`CNY,`0.00,`0.00,`2008450740201411110000174436,`1415635270-1,`10.25,`0.00,`ORIGINAL,`SUCCESS,`被扫支付测试,`订单额外描述,`-0.06000,`0.60%,`0.00,`10.25,`\r\n" +
+	"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\r\n" +
+	"`2,`0.01,`10.25,`0.00,`-0.06000,`0.01,`10.25\r\n"
+
+// 启动模拟的账单下载服务器，校验请求签名后返回指定的响应内容
+func mockBillServer(t *testing.T, handle func(path string, req map[string]string) []byte) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		req, err := util.XMLToMap(body)
+		assert.Nil(t, err)
+		assert.True(t, util.VerifyParamSign(req, testPayKey), "请求签名不匹配")
+		_, _ = w.Write(handle(r.URL.Path, req))
+	}))
+
+	old := baseURL
+	baseURL = server.URL
+	return func() {
+		baseURL = old
+		server.Close()
+	}
+}
+
+func TestPay_DownloadBill(t *testing.T) {
+	defer mockBillServer(t, func(path string, req map[string]string) []byte {
+		assert.Equal(t, pathDownloadBill, path)
+		assert.Equal(t, "20141110", req["bill_date"])
+		assert.Equal(t, "ALL", req["bill_type"])
+		assert.Equal(t, "GZIP", req["tar_type"])
+
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		_, _ = gw.Write([]byte(testBill))
+		_ = gw.Close()
+		return buf.Bytes()
+	})()
+
+	body, err := newTestPay().DownloadBill("20141110", BillTypeAll, true)
+	assert.Nil(t, err)
+	defer body.Close()
+
+	var records []*BillRecord
+	br := NewBillReader(body)
+	for br.Next() {
+		records = append(records, br.Record())
+	}
+	assert.Nil(t, br.Err())
+	assert.Len(t, records, 2)
+
+	paid := records[0]
+	assert.Equal(t, "1415640626", paid.OutTradeNo)
+	assert.Equal(t, TradeStateSuccess, paid.TradeState)
+	assert.Equal(t, int64(1), paid.TotalFee)
+	assert.Equal(t, "2014-11-10T16:33:45+08:00", paid.TradeTime.Format("2006-01-02T15:04:05Z07:00"))
+
+	refund := records[1]
+	assert.Equal(t, "1415635270-1", refund.OutRefundNo)
+	assert.Equal(t, int64(1025), refund.RefundFee)
+	assert.Equal(t, int64(-6), refund.ServiceCharge)
+	assert.Equal(t, msg.RefundStatusSuccess, refund.RefundStatus)
+
+	assert.Equal(t, &BillSummary{
+		TotalCount:         2,
+		SettlementTotalFee: 1,
+		RefundFee:          1025,
+		ServiceCharge:      -6,
+		TotalFee:           1,
+		ApplyRefundFee:     1025,
+	}, br.Summary())
+}
+
+func TestPay_DownloadBillError(t *testing.T) {
+	defer mockBillServer(t, func(string, map[string]string) []byte {
+		return []byte("<xml><return_code><![CDATA[FAIL]]></return_code><return_msg><![CDATA[No Bill Exist]]></return_msg><error_code><![CDATA[20002]]></error_code></xml>")
+	})()
+
+	_, err := newTestPay().DownloadBill("20141110", BillTypeAll, true)
+	assert.Equal(t, "20002", err.(*Error).ErrCode)
+}
+
+func TestFundFlowReader(t *testing.T) {
+	fr := NewFundFlowReader(strings.NewReader("记账时间,微信支付业务单号,资金流水单号,业务名称,业务类型,收支类型,收支金额（元）,账户结余（元）,资金变更提交申请人,备注,业务凭证号\n" +
+		"`2018-02-01 04:21:23,`50000305742018020103387128253,`1900009231201802015884652186,`退款,`退款,`支出,`0.02,`0.17,`system,`缺货,`REF4200000068201801293084726067\n" +
+		"资金流水总笔数,收入笔数,收入金额,支出笔数,支出金额\n" +
+		"`1,`0,`0.00,`1,`0.02\n"))
+
+	assert.True(t, fr.Next())
+	assert.Equal(t, int64(2), fr.Record().Amount)
+	assert.Equal(t, "支出", fr.Record().FlowType)
+	assert.False(t, fr.Next())
+	assert.Nil(t, fr.Err())
+	assert.Equal(t, &FundFlowSummary{TotalCount: 1, ExpenseCount: 1, ExpenseAmount: 2}, fr.Summary())
+}
@@ -0,0 +1,64 @@
+package pay
+
+import (
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gotid/wechat/util"
+	"golang.org/x/crypto/pkcs12"
+)
+
+// 已加载商户证书的 http 客户端，以商户号和证书摘要为键
+var tlsClients sync.Map
+
+// 返回携带商户证书的 http 客户端，证书密码为商户号。
+// 仿真测试系统不校验商户证书，沙箱模式下未配置证书时使用默认客户端。
+func (p *Pay) tlsClient() (*http.Client, error) {
+	if p.PaySandbox && len(p.P12) == 0 {
+		return util.HTTPClient, nil
+	}
+	if len(p.P12) == 0 {
+		return nil, fmt.Errorf("商户 %s 未配置支付证书", p.PayMchID)
+	}
+
+	key := fmt.Sprintf("%s:%x", p.PayMchID, md5.Sum(p.P12))
+	if client, ok := tlsClients.Load(key); ok {
+		return client.(*http.Client), nil
+	}
+
+	cert, err := p12ToCertificate(p.P12, p.PayMchID)
+	if err != nil {
+		return nil, fmt.Errorf("加载商户 %s 的支付证书失败：%v", p.PayMchID, err)
+	}
+
+	client := &http.Client{
+		Timeout: util.DefaultHTTPTimeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				Certificates: []tls.Certificate{cert},
+			},
+		},
+	}
+	actual, _ := tlsClients.LoadOrStore(key, client)
+	return actual.(*http.Client), nil
+}
+
+// 将 p12 证书转换为 tls 证书
+func p12ToCertificate(p12 []byte, password string) (tls.Certificate, error) {
+	blocks, err := pkcs12.ToPEM(p12, password)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	var pemData []byte
+	for _, b := range blocks {
+		pemData = append(pemData, pem.EncodeToMemory(b)...)
+	}
+
+	return tls.X509KeyPair(pemData, pemData)
+}
@@ -0,0 +1,31 @@
+package pay
+
+import "fmt"
+
+// 常见的业务错误，可通过 errors.Is 判断
+var (
+	ErrSystemError  = &Error{ErrCode: "SYSTEMERROR"}   // 系统繁忙，结果不确定，须以原单号重试或查询
+	ErrNotEnough    = &Error{ErrCode: "NOTENOUGH"}     // 商户余额不足
+	ErrNameMismatch = &Error{ErrCode: "NAME_MISMATCH"} // 收款用户姓名校验不一致
+)
+
+// Error 微信支付接口错误，通信失败时仅有返回状态码和返回信息
+type Error struct {
+	ReturnCode string // 返回状态码
+	ReturnMsg  string // 返回信息
+	ErrCode    string // 业务错误代码
+	ErrCodeDes string // 业务错误代码描述
+}
+
+func (e *Error) Error() string {
+	if e.ErrCode == "" {
+		return fmt.Sprintf("微信支付通信失败：return_code=%s, return_msg=%s", e.ReturnCode, e.ReturnMsg)
+	}
+	return fmt.Sprintf("微信支付业务失败：err_code=%s, err_code_des=%s", e.ErrCode, e.ErrCodeDes)
+}
+
+// Is 业务错误代码相同时视为同一错误
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.ErrCode != "" && t.ErrCode == e.ErrCode
+}
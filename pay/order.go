@@ -0,0 +1,153 @@
+package pay
+
+import (
+	"github.com/gotid/god/lib/gconv"
+	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/util"
+)
+
+const (
+	pathOrderQuery = "/pay/orderquery"
+	pathCloseOrder = "/pay/closeorder"
+	pathReverse    = "/secapi/pay/reverse"
+)
+
+// TradeState 交易状态
+type TradeState string
+
+const (
+	TradeStateSuccess    TradeState = "SUCCESS"    // 支付成功
+	TradeStateRefund     TradeState = "REFUND"     // 转入退款
+	TradeStateNotPay     TradeState = "NOTPAY"     // 未支付
+	TradeStateClosed     TradeState = "CLOSED"     // 已关闭
+	TradeStateRevoked    TradeState = "REVOKED"    // 已撤销（付款码支付）
+	TradeStateUserPaying TradeState = "USERPAYING" // 用户支付中（付款码支付）
+	TradeStatePayError   TradeState = "PAYERROR"   // 支付失败
+)
+
+// Paid 是否已支付，转入退款的订单也曾支付成功
+func (s TradeState) Paid() bool {
+	return s == TradeStateSuccess || s == TradeStateRefund
+}
+
+// Final 是否为不再变化的终态
+func (s TradeState) Final() bool {
+	switch s {
+	case TradeStateNotPay, TradeStateUserPaying:
+		return false
+	default:
+		return true
+	}
+}
+
+// OrderQueryResult 订单查询结果
+// https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_2
+type OrderQueryResult struct {
+	TradeState         TradeState   // 交易状态
+	TradeStateDesc     string       // 交易状态描述
+	DeviceInfo         string       // 设备号
+	OpenID             string       // 用户标识
+	IsSubscribe        string       // 是否关注公众账号 Y/N
+	SubMchID           string       // 子商户号，服务商模式时返回
+	SubAppID           string       // 子商户应用ID，服务商模式时返回
+	SubOpenID          string       // 用户在子商户应用下的标识，服务商模式时返回
+	SubIsSubscribe     string       // 是否关注子商户公众账号 Y/N，服务商模式时返回
+	TradeType          TradeType    // 交易类型
+	BankType           string       // 付款银行
+	TotalFee           int64        // 订单金额，单位分
+	SettlementTotalFee int64        // 应结订单金额，单位分
+	FeeType            string       // 标价币种
+	CashFee            int64        // 现金支付金额，单位分
+	CashFeeType        string       // 现金支付币种
+	CouponFee          int64        // 代金券金额，单位分
+	CouponCount        int          // 代金券使用数量
+	Coupons            []msg.Coupon // 代金券列表
+	TransactionID      string       // 微信支付订单号
+	OutTradeNo         string       // 商户订单号
+	Attach             string       // 附加数据
+	TimeEnd            string       // 支付完成时间 yyyyMMddHHmmss
+}
+
+// QueryOrderByTransactionID 按微信支付订单号查询订单
+func (p *Pay) QueryOrderByTransactionID(transactionID string) (*OrderQueryResult, error) {
+	return p.queryOrder(map[string]string{"transaction_id": transactionID})
+}
+
+// QueryOrderByOutTradeNo 按商户订单号查询订单
+func (p *Pay) QueryOrderByOutTradeNo(outTradeNo string) (*OrderQueryResult, error) {
+	return p.queryOrder(map[string]string{"out_trade_no": outTradeNo})
+}
+
+func (p *Pay) queryOrder(params map[string]string) (*OrderQueryResult, error) {
+	m, err := p.post(pathOrderQuery, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OrderQueryResult{
+		TradeState:         TradeState(m["trade_state"]),
+		TradeStateDesc:     m["trade_state_desc"],
+		DeviceInfo:         m["device_info"],
+		OpenID:             m["openid"],
+		IsSubscribe:        m["is_subscribe"],
+		SubMchID:           m["sub_mch_id"],
+		SubAppID:           m["sub_appid"],
+		SubOpenID:          m["sub_openid"],
+		SubIsSubscribe:     m["sub_is_subscribe"],
+		TradeType:          TradeType(m["trade_type"]),
+		BankType:           m["bank_type"],
+		TotalFee:           gconv.Int64(m["total_fee"]),
+		SettlementTotalFee: gconv.Int64(m["settlement_total_fee"]),
+		FeeType:            m["fee_type"],
+		CashFee:            gconv.Int64(m["cash_fee"]),
+		CashFeeType:        m["cash_fee_type"],
+		CouponFee:          gconv.Int64(m["coupon_fee"]),
+		CouponCount:        gconv.Int(m["coupon_count"]),
+		TransactionID:      m["transaction_id"],
+		OutTradeNo:         m["out_trade_no"],
+		Attach:             m["attach"],
+		TimeEnd:            m["time_end"],
+	}
+	result.Coupons, err = msg.ParseCoupons(m, result.CouponCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// CloseOrder 关闭未支付的订单，订单生成后 5 分钟内不能关闭
+func (p *Pay) CloseOrder(outTradeNo string) error {
+	_, err := p.post(pathCloseOrder, map[string]string{"out_trade_no": outTradeNo})
+	return err
+}
+
+// Reverse 撤销付款码支付订单，需要商户证书。
+// 微信支付订单号和商户订单号二选一，业务失败时同样返回是否需要继续调用撤销。
+func (p *Pay) Reverse(transactionID, outTradeNo string) (recall bool, err error) {
+	client, err := p.tlsClient()
+	if err != nil {
+		return false, err
+	}
+
+	params := map[string]string{
+		"transaction_id": transactionID,
+		"out_trade_no":   outTradeNo,
+	}
+	data, err := p.postRaw(pathReverse, params, client)
+	if err != nil {
+		return false, err
+	}
+
+	m, err := p.parseResponse(data, params["sign_type"])
+	if _, ok := err.(*Error); ok {
+		if raw, e := util.XMLToMap(data); e == nil {
+			return raw["recall"] == "Y", err
+		}
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return m["recall"] == "Y", nil
+}
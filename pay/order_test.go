@@ -0,0 +1,64 @@
+package pay
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/gotid/wechat/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPay_QueryOrder(t *testing.T) {
+	defer mockServer(t, func(path string, req map[string]string) map[string]string {
+		assert.Equal(t, pathOrderQuery, path)
+		assert.Equal(t, "1009660380201506130728806387", req["transaction_id"])
+
+		return map[string]string{
+			"return_code":    "SUCCESS",
+			"result_code":    "SUCCESS",
+			"trade_state":    "SUCCESS",
+			"openid":         "oUpF8uN95-Ptaags6E_roPHg7AG0",
+			"trade_type":     "JSAPI",
+			"total_fee":      "101",
+			"cash_fee":       "100",
+			"coupon_fee":     "1",
+			"coupon_count":   "1",
+			"coupon_id_0":    "10000",
+			"coupon_fee_0":   "1",
+			"transaction_id": req["transaction_id"],
+			"out_trade_no":   "1415757673",
+			"time_end":       "20141111170043",
+		}
+	})()
+
+	result, err := newTestPay().QueryOrderByTransactionID("1009660380201506130728806387")
+	assert.Nil(t, err)
+	assert.Equal(t, TradeStateSuccess, result.TradeState)
+	assert.True(t, result.TradeState.Paid())
+	assert.Equal(t, int64(101), result.TotalFee)
+	assert.Equal(t, "1415757673", result.OutTradeNo)
+	assert.Equal(t, []msg.Coupon{{ID: "10000", Fee: 1}}, result.Coupons)
+}
+
+func TestPay_Reverse(t *testing.T) {
+	defer mockServer(t, func(path string, req map[string]string) map[string]string {
+		assert.Equal(t, pathReverse, path)
+		return map[string]string{
+			"return_code":  "SUCCESS",
+			"result_code":  "FAIL",
+			"err_code":     "SYSTEMERROR",
+			"err_code_des": "系统错误",
+			"recall":       "Y",
+		}
+	})()
+
+	p := newTestPay()
+	_, err := p.Reverse("", "1415757673")
+	assert.NotNil(t, err, "未配置证书时不能撤销")
+
+	p.P12, err = ioutil.ReadFile("testdata/apiclient_cert.p12")
+	assert.Nil(t, err)
+	recall, err := p.Reverse("", "1415757673")
+	assert.True(t, recall)
+	assert.Equal(t, "SYSTEMERROR", err.(*Error).ErrCode)
+}
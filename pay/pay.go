@@ -0,0 +1,207 @@
+// Package pay 提供微信支付 v2 商户接口。
+package pay
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/gotid/god/lib/grand"
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/util"
+)
+
+// 微信支付接口地址
+var baseURL = "https://api.mch.weixin.qq.com"
+
+// Pay 微信支付控制器
+type Pay struct {
+	*context.Context
+	subAppID string // 服务商模式下的子商户应用ID
+	subMchID string // 服务商模式下的子商户号
+}
+
+// NewPay 返回一个新的微信支付控制器
+func NewPay(ctx *context.Context) *Pay {
+	return &Pay{Context: ctx}
+}
+
+// SubMerchant 返回服务商模式下代子商户交易的微信支付控制器，与当前控制器共享服务商上下文。
+// 子商户应用ID 可为空，此时以服务商应用下的 openid 下单。
+func (p *Pay) SubMerchant(subAppID, subMchID string) *Pay {
+	return &Pay{Context: p.Context, subAppID: subAppID, subMchID: subMchID}
+}
+
+// SubAppID 返回服务商模式下的子商户应用ID
+func (p *Pay) SubAppID() string {
+	return p.subAppID
+}
+
+// SubMchID 返回服务商模式下的子商户号，普通商户模式时为空
+func (p *Pay) SubMchID() string {
+	return p.subMchID
+}
+
+// 投递微信支付请求：补全公共参数并签名，校验响应的通信结果、签名和业务结果
+func (p *Pay) post(path string, params map[string]string) (map[string]string, error) {
+	data, err := p.postRaw(path, params, util.HTTPClient)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseResponse(data, params["sign_type"])
+}
+
+// 携带商户证书投递微信支付请求，校验响应的通信结果、签名和业务结果
+func (p *Pay) postWithCert(path string, params map[string]string) (map[string]string, error) {
+	client, err := p.tlsClient()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := p.postRaw(path, params, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseResponse(data, params["sign_type"])
+}
+
+// 携带商户证书投递微信支付请求，响应不含签名，仅校验通信结果和业务结果
+func (p *Pay) postWithCertUnsigned(path string, params map[string]string) (map[string]string, error) {
+	client, err := p.tlsClient()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := p.postRaw(path, params, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseResponse(data, nil)
+}
+
+// 投递微信支付请求并返回原始响应数据
+func (p *Pay) postRaw(path string, params map[string]string, client *http.Client) ([]byte, error) {
+	body, err := p.postStream(path, params, client)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+// 投递微信支付请求并返回响应数据流，由调用方负责关闭
+func (p *Pay) postStream(path string, params map[string]string, client *http.Client) (io.ReadCloser, error) {
+	if err := p.sign(params); err != nil {
+		return nil, err
+	}
+
+	uri := p.url(path)
+	resp, err := client.Post(uri, "application/xml; charset=utf-8", bytes.NewReader(util.MapToXML(params)))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("微信支付请求错误：网址=%s, 状态码=%d", uri, resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
+
+// 补全公共参数并签名
+func (p *Pay) sign(params map[string]string) error {
+	// 企业付款接口以 mch_appid 和 mchid 标识商户
+	if _, ok := params["mch_appid"]; !ok {
+		if params["appid"] == "" {
+			params["appid"] = p.AppID
+		}
+		if params["mch_id"] == "" {
+			params["mch_id"] = p.PayMchID
+		}
+		if params["sub_appid"] == "" {
+			params["sub_appid"] = p.subAppID
+		}
+		if params["sub_mch_id"] == "" {
+			params["sub_mch_id"] = p.subMchID
+		}
+	}
+	if params["nonce_str"] == "" {
+		params["nonce_str"] = grand.S(32)
+	}
+
+	key, err := p.SignKey()
+	if err != nil {
+		return err
+	}
+	sign, err := util.ParamSign(params, key)
+	if err != nil {
+		return err
+	}
+	params["sign"] = sign
+
+	return nil
+}
+
+// 解析响应数据，校验通信结果、签名和业务结果
+// 响应中不含签名类型，需按请求的签名类型校验
+func (p *Pay) parseResponse(data []byte, signType string) (map[string]string, error) {
+	return parseResponse(data, func(m map[string]string) bool {
+		return p.verify(m, signType)
+	})
+}
+
+// 解析响应数据，校验通信结果、业务结果，以及 verify 不为空时的签名
+func parseResponse(data []byte, verify func(map[string]string) bool) (map[string]string, error) {
+	m, err := util.XMLToMap(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析微信支付响应失败：data=%s, err=%v", data, err)
+	}
+
+	if m["return_code"] != "SUCCESS" {
+		return nil, &Error{
+			ReturnCode: m["return_code"],
+			ReturnMsg:  m["return_msg"],
+		}
+	}
+
+	if verify != nil && !verify(m) {
+		return nil, fmt.Errorf("微信支付响应签名不匹配：data=%s", data)
+	}
+
+	if m["result_code"] != "" && m["result_code"] != "SUCCESS" {
+		return nil, &Error{
+			ReturnCode: m["return_code"],
+			ReturnMsg:  m["return_msg"],
+			ErrCode:    m["err_code"],
+			ErrCodeDes: m["err_code_des"],
+		}
+	}
+
+	return m, nil
+}
+
+// 按指定签名类型校验参数中的签名
+func (p *Pay) verify(params map[string]string, signType string) bool {
+	if signType == "" {
+		signType = util.SignTypeMD5
+	}
+
+	key, err := p.SignKey()
+	if err != nil {
+		return false
+	}
+	sign, err := util.CalculateSign(util.OrderParam(params, "&key="+key), signType, key)
+	if err != nil {
+		return false
+	}
+
+	return params["sign"] != "" && hmac.Equal([]byte(params["sign"]), []byte(sign))
+}
@@ -0,0 +1,172 @@
+package pay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const testPayKey = "192006250b4c09247ec02edce69f6a2d"
+
+func newTestPay() *Pay {
+	return NewPay(&context.Context{
+		AppID:        "wx2421b1c4370ec43b",
+		PayMchID:     "10000100",
+		PayKey:       testPayKey,
+		PayNotifyURL: "https://example.com/notify",
+	})
+}
+
+// 启动模拟的微信支付服务器，校验请求签名后返回签名的响应
+func mockServer(t *testing.T, handle func(path string, req map[string]string) map[string]string) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		req, err := util.XMLToMap(body)
+		assert.Nil(t, err)
+		assert.True(t, util.VerifyParamSign(req, testPayKey), "请求签名不匹配")
+
+		resp := handle(r.URL.Path, req)
+		if _, ok := resp["sign"]; !ok {
+			signType := req["sign_type"]
+			if signType == "" {
+				signType = util.SignTypeMD5
+			}
+			sign, err := util.CalculateSign(util.OrderParam(resp, "&key="+testPayKey), signType, testPayKey)
+			assert.Nil(t, err)
+			resp["sign"] = sign
+		}
+		_, _ = w.Write(util.MapToXML(resp))
+	}))
+
+	old := baseURL
+	baseURL = server.URL
+	return func() {
+		baseURL = old
+		server.Close()
+	}
+}
+
+func TestPay_UnifiedOrder(t *testing.T) {
+	defer mockServer(t, func(path string, req map[string]string) map[string]string {
+		assert.Equal(t, pathUnifiedOrder, path)
+		assert.Equal(t, "wx2421b1c4370ec43b", req["appid"])
+		assert.Equal(t, "10000100", req["mch_id"])
+		assert.Equal(t, "https://example.com/notify", req["notify_url"])
+		assert.Equal(t, "JSAPI", req["trade_type"])
+		assert.Equal(t, "100", req["total_fee"])
+
+		return map[string]string{
+			"return_code": "SUCCESS",
+			"result_code": "SUCCESS",
+			"appid":       req["appid"],
+			"mch_id":      req["mch_id"],
+			"nonce_str":   "IITRi8Iabbblz1Jc",
+			"trade_type":  "JSAPI",
+			"prepay_id":   "wx201411101639507cbf6ffd8b0779950874",
+		}
+	})()
+
+	p := newTestPay()
+	result, err := p.UnifiedOrder(&Order{
+		TradeType:      TradeTypeJSAPI,
+		Body:           "测试商品",
+		OutTradeNo:     "20150806125346",
+		TotalFee:       100,
+		SpbillCreateIP: "123.12.12.123",
+		OpenID:         "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o",
+		SignType:       util.SignTypeHMACSHA256,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "wx201411101639507cbf6ffd8b0779950874", result.PrepayID)
+
+	params, err := p.MiniProgramParams(result.PrepayID)
+	assert.Nil(t, err)
+	assert.Equal(t, "prepay_id=wx201411101639507cbf6ffd8b0779950874", params.Package)
+	sign, err := util.ParamSign(map[string]string{
+		"appId":     p.AppID,
+		"timeStamp": params.TimeStamp,
+		"nonceStr":  params.NonceStr,
+		"package":   params.Package,
+		"signType":  params.SignType,
+	}, testPayKey)
+	assert.Nil(t, err)
+	assert.Equal(t, sign, params.PaySign)
+}
+
+func TestPay_UnifiedOrderError(t *testing.T) {
+	defer mockServer(t, func(string, map[string]string) map[string]string {
+		return map[string]string{
+			"return_code":  "SUCCESS",
+			"result_code":  "FAIL",
+			"err_code":     "ORDERPAID",
+			"err_code_des": "商户订单已支付",
+		}
+	})()
+
+	_, err := newTestPay().UnifiedOrder(&Order{TradeType: TradeTypeNative})
+	assert.Equal(t, &Error{ReturnCode: "SUCCESS", ErrCode: "ORDERPAID", ErrCodeDes: "商户订单已支付"}, err)
+
+	// 响应签名错误
+	defer mockServer(t, func(string, map[string]string) map[string]string {
+		return map[string]string{"return_code": "SUCCESS", "result_code": "SUCCESS", "sign": "invalid"}
+	})()
+	_, err = newTestPay().UnifiedOrder(&Order{TradeType: TradeTypeNative})
+	assert.NotNil(t, err)
+}
+
+func TestPay_SubMerchant(t *testing.T) {
+	defer mockServer(t, func(path string, req map[string]string) map[string]string {
+		assert.Equal(t, "wx2421b1c4370ec43b", req["appid"])
+		assert.Equal(t, "10000100", req["mch_id"])
+		assert.Equal(t, "wx8888888888888888", req["sub_appid"])
+		assert.Equal(t, "1900000109", req["sub_mch_id"])
+
+		switch path {
+		case pathUnifiedOrder:
+			assert.Equal(t, "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o", req["sub_openid"])
+			assert.Empty(t, req["openid"])
+			return map[string]string{"return_code": "SUCCESS", "result_code": "SUCCESS", "trade_type": "JSAPI", "prepay_id": "wx201410272009395522657a690389285100"}
+		case pathOrderQuery:
+			return map[string]string{
+				"return_code": "SUCCESS",
+				"result_code": "SUCCESS",
+				"sub_appid":   req["sub_appid"],
+				"sub_mch_id":  req["sub_mch_id"],
+				"sub_openid":  "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o",
+				"trade_state": "SUCCESS",
+			}
+		default:
+			t.Fatalf("未知的接口 %s", path)
+			return nil
+		}
+	})()
+
+	sp := newTestPay()
+	p := sp.SubMerchant("wx8888888888888888", "1900000109")
+	assert.Empty(t, sp.SubMchID(), "不影响服务商自身的控制器")
+
+	result, err := p.UnifiedOrder(&Order{
+		TradeType:      TradeTypeJSAPI,
+		Body:           "腾讯充值中心-QQ会员充值",
+		OutTradeNo:     "20150806125346",
+		TotalFee:       88,
+		SpbillCreateIP: "123.12.12.123",
+		SubOpenID:      "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o",
+	})
+	assert.Nil(t, err)
+
+	params, err := p.JSAPIParams(result.PrepayID)
+	assert.Nil(t, err)
+	assert.Equal(t, "wx8888888888888888", params.AppID)
+
+	order, err := p.QueryOrderByOutTradeNo("20150806125346")
+	assert.Nil(t, err)
+	assert.Equal(t, "1900000109", order.SubMchID)
+	assert.Equal(t, "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o", order.SubOpenID)
+}
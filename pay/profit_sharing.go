@@ -0,0 +1,314 @@
+package pay
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/gotid/god/lib/gconv"
+	"github.com/gotid/wechat/util"
+)
+
+const (
+	pathProfitSharingAddReceiver    = "/pay/profitsharingaddreceiver"
+	pathProfitSharingRemoveReceiver = "/pay/profitsharingremovereceiver"
+	pathProfitSharing               = "/secapi/pay/profitsharing"
+	pathMultiProfitSharing          = "/secapi/pay/multiprofitsharing"
+	pathProfitSharingFinish         = "/secapi/pay/profitsharingfinish"
+	pathProfitSharingQuery          = "/pay/profitsharingquery"
+	pathProfitSharingReturn         = "/secapi/pay/profitsharingreturn"
+	pathProfitSharingReturnQuery    = "/pay/profitsharingreturnquery"
+)
+
+// ReceiverType 分账接收方类型
+type ReceiverType string
+
+const (
+	ReceiverTypeMerchant       ReceiverType = "MERCHANT_ID"         // 商户号
+	ReceiverTypePersonalOpenID ReceiverType = "PERSONAL_OPENID"     // 个人 openid，由服务商 appid 转换得到
+	ReceiverTypePersonalSubID  ReceiverType = "PERSONAL_SUB_OPENID" // 个人 sub_openid，由子商户 appid 转换得到
+)
+
+// RelationType 与分账方的关系类型
+type RelationType string
+
+const (
+	RelationServiceProvider RelationType = "SERVICE_PROVIDER" // 服务商
+	RelationStore           RelationType = "STORE"            // 门店
+	RelationStaff           RelationType = "STAFF"            // 员工
+	RelationStoreOwner      RelationType = "STORE_OWNER"      // 店主
+	RelationPartner         RelationType = "PARTNER"          // 合作伙伴
+	RelationHeadquarter     RelationType = "HEADQUARTER"      // 总部
+	RelationBrand           RelationType = "BRAND"            // 品牌方
+	RelationDistributor     RelationType = "DISTRIBUTOR"      // 分销商
+	RelationUser            RelationType = "USER"             // 用户
+	RelationSupplier        RelationType = "SUPPLIER"         // 供应商
+	RelationCustom          RelationType = "CUSTOM"           // 自定义
+)
+
+// ProfitSharingStatus 分账单状态
+type ProfitSharingStatus string
+
+const (
+	ProfitSharingAccepted   ProfitSharingStatus = "ACCEPTED"   // 受理成功
+	ProfitSharingProcessing ProfitSharingStatus = "PROCESSING" // 处理中
+	ProfitSharingFinished   ProfitSharingStatus = "FINISHED"   // 处理完成
+	ProfitSharingClosed     ProfitSharingStatus = "CLOSED"     // 处理失败，已关单
+)
+
+// ReceiverResult 分账接收方的分账结果
+type ReceiverResult string
+
+const (
+	ReceiverResultPending ReceiverResult = "PENDING" // 待分账
+	ReceiverResultSuccess ReceiverResult = "SUCCESS" // 分账成功
+	ReceiverResultClosed  ReceiverResult = "CLOSED"  // 已关闭
+)
+
+// ReturnResult 分账回退结果
+type ReturnResult string
+
+const (
+	ReturnResultProcessing ReturnResult = "PROCESSING" // 处理中
+	ReturnResultSuccess    ReturnResult = "SUCCESS"    // 已成功
+	ReturnResultFailed     ReturnResult = "FAILED"     // 已失败
+)
+
+type (
+	// ProfitSharingReceiver 分账接收方
+	// https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_3&index=4
+	ProfitSharingReceiver struct {
+		Type           ReceiverType `json:"type"`                      // 接收方类型，必填
+		Account        string       `json:"account"`                   // 接收方账号，必填
+		Name           string       `json:"name,omitempty"`            // 接收方全称，商户号时必填
+		RelationType   RelationType `json:"relation_type,omitempty"`   // 与分账方的关系类型，添加时必填
+		CustomRelation string       `json:"custom_relation,omitempty"` // 自定义的分账关系，关系类型为 CUSTOM 时必填
+	}
+
+	// ProfitSharingItem 单个接收方的分账
+	ProfitSharingItem struct {
+		Type        ReceiverType   `json:"type"`                  // 接收方类型
+		Account     string         `json:"account"`               // 接收方账号
+		Amount      int64          `json:"amount"`                // 分账金额，单位分
+		Description string         `json:"description"`           // 分账描述
+		Result      ReceiverResult `json:"result,omitempty"`      // 分账结果，仅查询返回
+		FinishTime  string         `json:"finish_time,omitempty"` // 分账完成时间，仅查询返回
+		FailReason  string         `json:"fail_reason,omitempty"` // 分账失败原因，仅查询返回
+	}
+
+	// ProfitSharing 请求分账参数
+	// https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_1&index=1
+	ProfitSharing struct {
+		TransactionID string               // 微信支付订单号，必填
+		OutOrderNo    string               // 商户分账单号，必填
+		Receivers     []*ProfitSharingItem // 分账接收方列表，必填
+	}
+
+	// ProfitSharingResult 分账结果
+	ProfitSharingResult struct {
+		TransactionID string               // 微信支付订单号
+		OutOrderNo    string               // 商户分账单号
+		OrderID       string               // 微信分账单号
+		Status        ProfitSharingStatus  // 分账单状态
+		CloseReason   string               // 关单原因，仅查询返回
+		Receivers     []*ProfitSharingItem // 分账接收方列表，仅查询返回
+		Amount        int64                // 完结分账的金额，单位分，仅查询返回
+		Description   string               // 完结分账的描述，仅查询返回
+	}
+
+	// ProfitSharingReturn 分账回退参数，微信分账单号和商户分账单号二选一
+	// https://pay.weixin.qq.com/wiki/doc/api/allocation.php?chapter=27_7&index=7
+	ProfitSharingReturn struct {
+		OrderID           string       // 微信分账单号
+		OutOrderNo        string       // 商户分账单号
+		OutReturnNo       string       // 商户回退单号，必填
+		ReturnAccountType ReceiverType // 回退方类型，目前仅支持商户号
+		ReturnAccount     string       // 回退方账号，必填
+		ReturnAmount      int64        // 回退金额，单位分，必填
+		Description       string       // 回退描述，必填
+	}
+
+	// ProfitSharingReturnResult 分账回退结果
+	ProfitSharingReturnResult struct {
+		OrderID           string       // 微信分账单号
+		OutOrderNo        string       // 商户分账单号
+		OutReturnNo       string       // 商户回退单号
+		ReturnNo          string       // 微信回退单号
+		ReturnAccountType ReceiverType // 回退方类型
+		ReturnAccount     string       // 回退方账号
+		ReturnAmount      int64        // 回退金额，单位分
+		Description       string       // 回退描述
+		Result            ReturnResult // 回退结果
+		FailReason        string       // 失败原因
+		FinishTime        string       // 完成时间
+	}
+)
+
+// AddProfitSharingReceiver 添加分账接收方
+func (p *Pay) AddProfitSharingReceiver(r *ProfitSharingReceiver) error {
+	receiver, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.post(pathProfitSharingAddReceiver, profitSharingParams(map[string]string{
+		"receiver": string(receiver),
+	}))
+	return err
+}
+
+// RemoveProfitSharingReceiver 删除分账接收方
+func (p *Pay) RemoveProfitSharingReceiver(receiverType ReceiverType, account string) error {
+	receiver, err := json.Marshal(&ProfitSharingReceiver{Type: receiverType, Account: account})
+	if err != nil {
+		return err
+	}
+
+	_, err = p.post(pathProfitSharingRemoveReceiver, profitSharingParams(map[string]string{
+		"receiver": string(receiver),
+	}))
+	return err
+}
+
+// ProfitSharing 请求单次分账，分账后剩余待分账金额自动解冻给本商户，需要商户证书。
+func (p *Pay) ProfitSharing(ps *ProfitSharing) (*ProfitSharingResult, error) {
+	return p.profitSharing(pathProfitSharing, ps)
+}
+
+// MultiProfitSharing 请求多次分账，分账后需调用 FinishProfitSharing 完结分账，需要商户证书。
+func (p *Pay) MultiProfitSharing(ps *ProfitSharing) (*ProfitSharingResult, error) {
+	return p.profitSharing(pathMultiProfitSharing, ps)
+}
+
+func (p *Pay) profitSharing(path string, ps *ProfitSharing) (*ProfitSharingResult, error) {
+	if len(ps.Receivers) == 0 {
+		return nil, fmt.Errorf("分账单 %s 缺少分账接收方", ps.OutOrderNo)
+	}
+
+	receivers, err := json.Marshal(ps.Receivers)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := p.postWithCert(path, profitSharingParams(map[string]string{
+		"transaction_id": ps.TransactionID,
+		"out_order_no":   ps.OutOrderNo,
+		"receivers":      string(receivers),
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProfitSharingResult{
+		TransactionID: m["transaction_id"],
+		OutOrderNo:    m["out_order_no"],
+		OrderID:       m["order_id"],
+		Status:        ProfitSharingStatus(m["status"]),
+	}, nil
+}
+
+// FinishProfitSharing 完结分账，将剩余待分账金额全部解冻给本商户，需要商户证书。
+func (p *Pay) FinishProfitSharing(transactionID, outOrderNo, description string) (*ProfitSharingResult, error) {
+	m, err := p.postWithCert(pathProfitSharingFinish, profitSharingParams(map[string]string{
+		"transaction_id": transactionID,
+		"out_order_no":   outOrderNo,
+		"amount":         "0",
+		"description":    description,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProfitSharingResult{
+		TransactionID: m["transaction_id"],
+		OutOrderNo:    m["out_order_no"],
+		OrderID:       m["order_id"],
+		Status:        ProfitSharingStatus(m["status"]),
+	}, nil
+}
+
+// QueryProfitSharing 查询分账结果
+func (p *Pay) QueryProfitSharing(transactionID, outOrderNo string) (*ProfitSharingResult, error) {
+	m, err := p.post(pathProfitSharingQuery, profitSharingParams(map[string]string{
+		"transaction_id": transactionID,
+		"out_order_no":   outOrderNo,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProfitSharingResult{
+		TransactionID: m["transaction_id"],
+		OutOrderNo:    m["out_order_no"],
+		OrderID:       m["order_id"],
+		Status:        ProfitSharingStatus(m["status"]),
+		CloseReason:   m["close_reason"],
+		Amount:        gconv.Int64(m["amount"]),
+		Description:   m["description"],
+	}
+	if m["receivers"] != "" {
+		if err := json.Unmarshal([]byte(m["receivers"]), &result.Receivers); err != nil {
+			return nil, fmt.Errorf("解析分账接收方失败：receivers=%s, err=%w", m["receivers"], err)
+		}
+	}
+
+	return result, nil
+}
+
+// ReturnProfitSharing 分账回退，将已分账给商户类型接收方的资金回退至本商户，需要商户证书。
+func (p *Pay) ReturnProfitSharing(r *ProfitSharingReturn) (*ProfitSharingReturnResult, error) {
+	if r.ReturnAccountType == "" {
+		r.ReturnAccountType = ReceiverTypeMerchant
+	}
+
+	m, err := p.postWithCert(pathProfitSharingReturn, profitSharingParams(map[string]string{
+		"order_id":            r.OrderID,
+		"out_order_no":        r.OutOrderNo,
+		"out_return_no":       r.OutReturnNo,
+		"return_account_type": string(r.ReturnAccountType),
+		"return_account":      r.ReturnAccount,
+		"return_amount":       strconv.FormatInt(r.ReturnAmount, 10),
+		"description":         r.Description,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return newProfitSharingReturnResult(m), nil
+}
+
+// QueryProfitSharingReturn 查询分账回退结果，微信分账单号和商户分账单号二选一
+func (p *Pay) QueryProfitSharingReturn(orderID, outOrderNo, outReturnNo string) (*ProfitSharingReturnResult, error) {
+	m, err := p.post(pathProfitSharingReturnQuery, profitSharingParams(map[string]string{
+		"order_id":      orderID,
+		"out_order_no":  outOrderNo,
+		"out_return_no": outReturnNo,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	return newProfitSharingReturnResult(m), nil
+}
+
+func newProfitSharingReturnResult(m map[string]string) *ProfitSharingReturnResult {
+	return &ProfitSharingReturnResult{
+		OrderID:           m["order_id"],
+		OutOrderNo:        m["out_order_no"],
+		OutReturnNo:       m["out_return_no"],
+		ReturnNo:          m["return_no"],
+		ReturnAccountType: ReceiverType(m["return_account_type"]),
+		ReturnAccount:     m["return_account"],
+		ReturnAmount:      gconv.Int64(m["return_amount"]),
+		Description:       m["description"],
+		Result:            ReturnResult(m["result"]),
+		FailReason:        m["fail_reason"],
+		FinishTime:        m["finish_time"],
+	}
+}
+
+// 分账接口仅支持 HMAC-SHA256 签名
+func profitSharingParams(params map[string]string) map[string]string {
+	params["sign_type"] = util.SignTypeHMACSHA256
+	return params
+}
@@ -0,0 +1,113 @@
+package pay
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPay_ProfitSharing(t *testing.T) {
+	defer mockServer(t, func(path string, req map[string]string) map[string]string {
+		assert.Equal(t, util.SignTypeHMACSHA256, req["sign_type"])
+
+		switch path {
+		case pathProfitSharingAddReceiver:
+			var receiver ProfitSharingReceiver
+			assert.Nil(t, json.Unmarshal([]byte(req["receiver"]), &receiver))
+			assert.Equal(t, ReceiverTypeMerchant, receiver.Type)
+			assert.Equal(t, RelationStore, receiver.RelationType)
+			return map[string]string{"return_code": "SUCCESS", "result_code": "SUCCESS", "receiver": req["receiver"]}
+		case pathMultiProfitSharing:
+			var receivers []*ProfitSharingItem
+			assert.Nil(t, json.Unmarshal([]byte(req["receivers"]), &receivers))
+			assert.Len(t, receivers, 2)
+			return map[string]string{
+				"return_code":    "SUCCESS",
+				"result_code":    "SUCCESS",
+				"transaction_id": req["transaction_id"],
+				"out_order_no":   req["out_order_no"],
+				"order_id":       "3008450740201411110007820472",
+				"status":         "PROCESSING",
+			}
+		case pathProfitSharingQuery:
+			return map[string]string{
+				"return_code":    "SUCCESS",
+				"result_code":    "SUCCESS",
+				"transaction_id": req["transaction_id"],
+				"out_order_no":   req["out_order_no"],
+				"order_id":       "3008450740201411110007820472",
+				"status":         "FINISHED",
+				"receivers":      `[{"type":"MERCHANT_ID","account":"190001001","amount":100,"description":"分给商户A","result":"SUCCESS","finish_time":"20180608170132"}]`,
+			}
+		default:
+			t.Fatalf("未知的接口 %s", path)
+			return nil
+		}
+	})()
+
+	p := newTestPay()
+	assert.Nil(t, p.AddProfitSharingReceiver(&ProfitSharingReceiver{
+		Type:         ReceiverTypeMerchant,
+		Account:      "190001001",
+		Name:         "示例商户全称",
+		RelationType: RelationStore,
+	}))
+
+	ps := &ProfitSharing{
+		TransactionID: "4208450740201411110007820472",
+		OutOrderNo:    "P20150806125346",
+		Receivers: []*ProfitSharingItem{
+			{Type: ReceiverTypeMerchant, Account: "190001001", Amount: 100, Description: "分给商户A"},
+			{Type: ReceiverTypePersonalOpenID, Account: "86693952", Amount: 888, Description: "分给个人"},
+		},
+	}
+	_, err := p.MultiProfitSharing(ps)
+	assert.NotNil(t, err, "未配置证书时不能分账")
+
+	p.P12, err = ioutil.ReadFile("testdata/apiclient_cert.p12")
+	assert.Nil(t, err)
+	result, err := p.MultiProfitSharing(ps)
+	assert.Nil(t, err)
+	assert.Equal(t, ProfitSharingProcessing, result.Status)
+
+	result, err = p.QueryProfitSharing(ps.TransactionID, ps.OutOrderNo)
+	assert.Nil(t, err)
+	assert.Equal(t, ProfitSharingFinished, result.Status)
+	assert.Equal(t, ReceiverResultSuccess, result.Receivers[0].Result)
+	assert.Equal(t, int64(100), result.Receivers[0].Amount)
+}
+
+func TestPay_ProfitSharingReturn(t *testing.T) {
+	defer mockServer(t, func(path string, req map[string]string) map[string]string {
+		assert.Equal(t, pathProfitSharingReturn, path)
+		assert.Equal(t, "MERCHANT_ID", req["return_account_type"])
+		return map[string]string{
+			"return_code":   "SUCCESS",
+			"result_code":   "SUCCESS",
+			"order_id":      "3008450740201411110007820472",
+			"out_return_no": req["out_return_no"],
+			"return_no":     "3008450740201411110007820472",
+			"return_amount": req["return_amount"],
+			"result":        "SUCCESS",
+		}
+	})()
+
+	p := newTestPay()
+	var err error
+	p.P12, err = ioutil.ReadFile("testdata/apiclient_cert.p12")
+	assert.Nil(t, err)
+
+	result, err := p.ReturnProfitSharing(&ProfitSharingReturn{
+		OrderID:       "3008450740201411110007820472",
+		OutReturnNo:   "R20190516001",
+		ReturnAccount: "86693852",
+		ReturnAmount:  888,
+		Description:   "用户退款",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ReturnResultSuccess, result.Result)
+	assert.Equal(t, int64(888), result.ReturnAmount)
+}
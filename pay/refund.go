@@ -0,0 +1,205 @@
+package pay
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gotid/god/lib/gconv"
+	"github.com/gotid/wechat/msg"
+)
+
+const (
+	pathRefund      = "/secapi/pay/refund"
+	pathRefundQuery = "/pay/refundquery"
+)
+
+type (
+	// Refund 申请退款参数，同一订单可分多次部分退款，每次使用不同的商户退款单号
+	// https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_4
+	Refund struct {
+		TransactionID string // 微信支付订单号，与商户订单号二选一
+		OutTradeNo    string // 商户订单号，与微信支付订单号二选一
+		OutRefundNo   string // 商户退款单号，必填
+		TotalFee      int64  // 订单金额，单位分，必填
+		RefundFee     int64  // 退款金额，单位分，必填
+		RefundFeeType string // 退款货币种类，默认 CNY
+		RefundDesc    string // 退款原因
+		RefundAccount string // 退款资金来源
+		NotifyURL     string // 退款结果通知地址，默认为上下文中的 PayRefundNotifyURL
+	}
+
+	// RefundResult 申请退款结果，退款是否成功需查询或等待退款结果通知
+	RefundResult struct {
+		TransactionID       string // 微信支付订单号
+		OutTradeNo          string // 商户订单号
+		OutRefundNo         string // 商户退款单号
+		RefundID            string // 微信退款单号
+		RefundFee           int64  // 申请退款金额，单位分
+		SettlementRefundFee int64  // 应结退款金额，单位分
+		TotalFee            int64  // 订单金额，单位分
+		SettlementTotalFee  int64  // 应结订单金额，单位分
+		FeeType             string // 标价币种
+		CashFee             int64  // 现金支付金额，单位分
+		CashRefundFee       int64  // 现金退款金额，单位分
+		CouponRefundFee     int64  // 代金券退款总金额，单位分
+		CouponRefundCount   int    // 退款代金券使用数量
+	}
+
+	// RefundQueryResult 退款查询结果
+	// https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_5
+	RefundQueryResult struct {
+		TransactionID    string          // 微信支付订单号
+		OutTradeNo       string          // 商户订单号
+		TotalFee         int64           // 订单金额，单位分
+		CashFee          int64           // 现金支付金额，单位分
+		TotalRefundCount int             // 订单总退款次数
+		Refunds          []*RefundRecord // 本次返回的退款记录
+	}
+
+	// RefundRecord 单笔退款记录
+	RefundRecord struct {
+		OutRefundNo         string           // 商户退款单号
+		RefundID            string           // 微信退款单号
+		RefundChannel       string           // 退款渠道 ORIGINAL/BALANCE/OTHER_BALANCE/OTHER_BANKCARD
+		RefundFee           int64            // 申请退款金额，单位分
+		SettlementRefundFee int64            // 退款金额，单位分
+		RefundStatus        msg.RefundStatus // 退款状态
+		RefundAccount       string           // 退款资金来源
+		RefundRecvAccout    string           // 退款入账账户
+		RefundSuccessTime   string           // 退款成功时间 yyyy-MM-dd HH:mm:ss
+	}
+)
+
+// Refund 申请退款，需要商户证书。
+func (p *Pay) Refund(r *Refund) (*RefundResult, error) {
+	if r.RefundFee <= 0 || r.RefundFee > r.TotalFee {
+		return nil, fmt.Errorf("退款金额 %d 须大于 0 且不超过订单金额 %d", r.RefundFee, r.TotalFee)
+	}
+
+	params := map[string]string{
+		"transaction_id":  r.TransactionID,
+		"out_trade_no":    r.OutTradeNo,
+		"out_refund_no":   r.OutRefundNo,
+		"total_fee":       strconv.FormatInt(r.TotalFee, 10),
+		"refund_fee":      strconv.FormatInt(r.RefundFee, 10),
+		"refund_fee_type": r.RefundFeeType,
+		"refund_desc":     r.RefundDesc,
+		"refund_account":  r.RefundAccount,
+		"notify_url":      r.NotifyURL,
+	}
+	if params["notify_url"] == "" {
+		params["notify_url"] = p.PayRefundNotifyURL
+	}
+
+	m, err := p.postWithCert(pathRefund, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RefundResult{
+		TransactionID:       m["transaction_id"],
+		OutTradeNo:          m["out_trade_no"],
+		OutRefundNo:         m["out_refund_no"],
+		RefundID:            m["refund_id"],
+		RefundFee:           gconv.Int64(m["refund_fee"]),
+		SettlementRefundFee: gconv.Int64(m["settlement_refund_fee"]),
+		TotalFee:            gconv.Int64(m["total_fee"]),
+		SettlementTotalFee:  gconv.Int64(m["settlement_total_fee"]),
+		FeeType:             m["fee_type"],
+		CashFee:             gconv.Int64(m["cash_fee"]),
+		CashRefundFee:       gconv.Int64(m["cash_refund_fee"]),
+		CouponRefundFee:     gconv.Int64(m["coupon_refund_fee"]),
+		CouponRefundCount:   gconv.Int(m["coupon_refund_count"]),
+	}, nil
+}
+
+// QueryRefundByOutTradeNo 按商户订单号查询退款，订单退款超过 10 笔时按偏移量分页
+func (p *Pay) QueryRefundByOutTradeNo(outTradeNo string, offset int) (*RefundQueryResult, error) {
+	params := map[string]string{"out_trade_no": outTradeNo}
+	if offset > 0 {
+		params["offset"] = strconv.Itoa(offset)
+	}
+	return p.queryRefund(params)
+}
+
+// QueryRefundByTransactionID 按微信支付订单号查询退款，订单退款超过 10 笔时按偏移量分页
+func (p *Pay) QueryRefundByTransactionID(transactionID string, offset int) (*RefundQueryResult, error) {
+	params := map[string]string{"transaction_id": transactionID}
+	if offset > 0 {
+		params["offset"] = strconv.Itoa(offset)
+	}
+	return p.queryRefund(params)
+}
+
+// QueryRefundByOutRefundNo 按商户退款单号查询退款
+func (p *Pay) QueryRefundByOutRefundNo(outRefundNo string) (*RefundQueryResult, error) {
+	return p.queryRefund(map[string]string{"out_refund_no": outRefundNo})
+}
+
+// QueryRefundByRefundID 按微信退款单号查询退款
+func (p *Pay) QueryRefundByRefundID(refundID string) (*RefundQueryResult, error) {
+	return p.queryRefund(map[string]string{"refund_id": refundID})
+}
+
+func (p *Pay) queryRefund(params map[string]string) (*RefundQueryResult, error) {
+	m, err := p.post(pathRefundQuery, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RefundQueryResult{
+		TransactionID:    m["transaction_id"],
+		OutTradeNo:       m["out_trade_no"],
+		TotalFee:         gconv.Int64(m["total_fee"]),
+		CashFee:          gconv.Int64(m["cash_fee"]),
+		TotalRefundCount: gconv.Int(m["total_refund_count"]),
+	}
+
+	count := gconv.Int(m["refund_count"])
+	for i := 0; i < count; i++ {
+		field := func(name string) string {
+			return m[fmt.Sprintf("%s_%d", name, i)]
+		}
+		result.Refunds = append(result.Refunds, &RefundRecord{
+			OutRefundNo:         field("out_refund_no"),
+			RefundID:            field("refund_id"),
+			RefundChannel:       field("refund_channel"),
+			RefundFee:           gconv.Int64(field("refund_fee")),
+			SettlementRefundFee: gconv.Int64(field("settlement_refund_fee")),
+			RefundStatus:        msg.RefundStatus(field("refund_status")),
+			RefundAccount:       field("refund_account"),
+			RefundRecvAccout:    field("refund_recv_accout"),
+			RefundSuccessTime:   field("refund_success_time"),
+		})
+	}
+	if result.TotalRefundCount == 0 {
+		result.TotalRefundCount = count
+	}
+
+	return result, nil
+}
+
+// Find 返回指定商户退款单号的退款记录
+func (r *RefundQueryResult) Find(outRefundNo string) *RefundRecord {
+	for _, record := range r.Refunds {
+		if record.OutRefundNo == outRefundNo {
+			return record
+		}
+	}
+	return nil
+}
+
+// RefundedFee 返回除指定商户退款单号外，已成功或处理中的退款金额合计
+func (r *RefundQueryResult) RefundedFee(excludeOutRefundNo string) int64 {
+	var total int64
+	for _, record := range r.Refunds {
+		if record.OutRefundNo == excludeOutRefundNo {
+			continue
+		}
+		switch record.RefundStatus {
+		case msg.RefundStatusSuccess, msg.RefundStatusProcessing:
+			total += record.RefundFee
+		}
+	}
+	return total
+}
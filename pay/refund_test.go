@@ -0,0 +1,79 @@
+package pay
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/gotid/wechat/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPay_Refund(t *testing.T) {
+	defer mockServer(t, func(path string, req map[string]string) map[string]string {
+		assert.Equal(t, pathRefund, path)
+		assert.Equal(t, "1415757673", req["out_trade_no"])
+		assert.Equal(t, "1415757673-1", req["out_refund_no"])
+		assert.Equal(t, "100", req["total_fee"])
+		assert.Equal(t, "30", req["refund_fee"])
+
+		return map[string]string{
+			"return_code":   "SUCCESS",
+			"result_code":   "SUCCESS",
+			"out_trade_no":  req["out_trade_no"],
+			"out_refund_no": req["out_refund_no"],
+			"refund_id":     "2008450740201411110000174436",
+			"refund_fee":    req["refund_fee"],
+			"total_fee":     req["total_fee"],
+			"cash_fee":      req["total_fee"],
+		}
+	})()
+
+	p := newTestPay()
+	refund := &Refund{OutTradeNo: "1415757673", OutRefundNo: "1415757673-1", TotalFee: 100, RefundFee: 30}
+	_, err := p.Refund(refund)
+	assert.NotNil(t, err, "未配置证书时不能退款")
+
+	p.P12, err = ioutil.ReadFile("testdata/apiclient_cert.p12")
+	assert.Nil(t, err)
+	result, err := p.Refund(refund)
+	assert.Nil(t, err)
+	assert.Equal(t, "2008450740201411110000174436", result.RefundID)
+	assert.Equal(t, int64(30), result.RefundFee)
+
+	_, err = p.Refund(&Refund{OutTradeNo: "1415757673", OutRefundNo: "1415757673-2", TotalFee: 100, RefundFee: 101})
+	assert.NotNil(t, err, "退款金额不能超过订单金额")
+}
+
+func TestPay_QueryRefund(t *testing.T) {
+	defer mockServer(t, func(path string, req map[string]string) map[string]string {
+		assert.Equal(t, pathRefundQuery, path)
+		assert.Equal(t, "1415757673", req["out_trade_no"])
+
+		return map[string]string{
+			"return_code":           "SUCCESS",
+			"result_code":           "SUCCESS",
+			"out_trade_no":          req["out_trade_no"],
+			"total_fee":             "100",
+			"refund_count":          "3",
+			"out_refund_no_0":       "1415757673-1",
+			"refund_fee_0":          "30",
+			"refund_status_0":       "SUCCESS",
+			"refund_success_time_0": "2016-07-25 15:26:26",
+			"out_refund_no_1":       "1415757673-2",
+			"refund_fee_1":          "20",
+			"refund_status_1":       "CHANGE",
+			"out_refund_no_2":       "1415757673-3",
+			"refund_fee_2":          "50",
+			"refund_status_2":       "PROCESSING",
+		}
+	})()
+
+	result, err := newTestPay().QueryRefundByOutTradeNo("1415757673", 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.TotalRefundCount)
+	assert.Len(t, result.Refunds, 3)
+	assert.Equal(t, msg.RefundStatusChange, result.Find("1415757673-2").RefundStatus)
+	assert.Nil(t, result.Find("1415757673-4"))
+	assert.Equal(t, int64(30), result.RefundedFee("1415757673-3"))
+	assert.Equal(t, int64(80), result.RefundedFee(""))
+}
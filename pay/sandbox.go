@@ -0,0 +1,115 @@
+package pay
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gotid/god/lib/gconv"
+	"github.com/gotid/god/lib/grand"
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/util"
+)
+
+const (
+	// 仿真测试系统的接口地址前缀
+	sandboxPrefix = "/sandboxnew"
+
+	pathGetSignKey = "/pay/getsignkey"
+
+	// 沙箱密钥的缓存时长
+	sandboxSignKeyTimeout = 24 * time.Hour
+)
+
+// 仿真测试系统中路径不同的接口
+var sandboxPaths = map[string]string{
+	pathRefund: "/pay/refund",
+}
+
+// 未配置缓存时在本地保存的沙箱密钥，以商户号和支付 key 为键
+var sandboxSignKeys sync.Map
+
+// SignKey 返回签名密钥，沙箱模式下为沙箱密钥，否则为商户支付 key
+func (p *Pay) SignKey() (string, error) {
+	if !p.PaySandbox {
+		return p.PayKey, nil
+	}
+	return p.SandboxSignKey()
+}
+
+// SandboxSignKey 返回仿真测试系统的沙箱密钥，优先读取缓存
+func (p *Pay) SandboxSignKey() (string, error) {
+	local := p.PayMchID + ":" + p.PayKey
+	if p.Cache != nil {
+		if v := p.Cache.Get(cache.KeyPaySandboxSignKey(p.PayMchID, p.PayKey)); v != nil {
+			return gconv.String(v), nil
+		}
+	} else if v, ok := sandboxSignKeys.Load(local); ok {
+		return v.(string), nil
+	}
+
+	key, err := p.getSandboxSignKey()
+	if err != nil {
+		return "", err
+	}
+
+	if p.Cache != nil {
+		if err := p.Cache.Set(cache.KeyPaySandboxSignKey(p.PayMchID, p.PayKey), key, sandboxSignKeyTimeout); err != nil {
+			return "", err
+		}
+	} else {
+		sandboxSignKeys.Store(local, key)
+	}
+	return key, nil
+}
+
+// 以商户支付 key 签名请求沙箱密钥
+func (p *Pay) getSandboxSignKey() (string, error) {
+	params := map[string]string{
+		"mch_id":    p.PayMchID,
+		"nonce_str": grand.S(32),
+	}
+	sign, err := util.ParamSign(params, p.PayKey)
+	if err != nil {
+		return "", err
+	}
+	params["sign"] = sign
+
+	uri := baseURL + sandboxPrefix + pathGetSignKey
+	resp, err := util.HTTPClient.Post(uri, "application/xml; charset=utf-8", bytes.NewReader(util.MapToXML(params)))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("微信支付请求错误：网址=%s, 状态码=%d", uri, resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	m, err := parseResponse(data, nil)
+	if err != nil {
+		return "", err
+	}
+	if m["sandbox_signkey"] == "" {
+		return "", fmt.Errorf("获取沙箱密钥失败：data=%s", data)
+	}
+	return m["sandbox_signkey"], nil
+}
+
+// 返回接口地址，沙箱模式下使用仿真测试系统
+func (p *Pay) url(path string) string {
+	if !p.PaySandbox {
+		return baseURL + path
+	}
+	if sandbox, ok := sandboxPaths[path]; ok {
+		path = sandbox
+	}
+	return baseURL + sandboxPrefix + path
+}
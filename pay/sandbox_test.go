@@ -0,0 +1,77 @@
+package pay
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPay_Sandbox(t *testing.T) {
+	const sandboxKey = "7b8a2f4c1d3e5f6a7b8c9d0e1f2a3b4c"
+
+	var signKeys int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		req, err := util.XMLToMap(body)
+		assert.Nil(t, err)
+
+		var resp map[string]string
+		switch r.URL.Path {
+		case sandboxPrefix + pathGetSignKey:
+			signKeys++
+			assert.True(t, util.VerifyParamSign(req, testPayKey), "沙箱密钥请求以商户支付 key 签名")
+			_, _ = w.Write(util.MapToXML(map[string]string{"return_code": "SUCCESS", "mch_id": req["mch_id"], "sandbox_signkey": sandboxKey}))
+			return
+		case sandboxPrefix + pathUnifiedOrder:
+			resp = map[string]string{"return_code": "SUCCESS", "result_code": "SUCCESS", "trade_type": "NATIVE", "prepay_id": "wx201410272009395522657a690389285100"}
+		case sandboxPrefix + "/pay/refund":
+			resp = map[string]string{"return_code": "SUCCESS", "result_code": "SUCCESS", "out_refund_no": req["out_refund_no"], "refund_fee": req["refund_fee"]}
+		default:
+			t.Fatalf("未知的接口 %s", r.URL.Path)
+		}
+
+		assert.True(t, util.VerifyParamSign(req, sandboxKey), "业务请求以沙箱密钥签名")
+		resp["sign"], err = util.CalculateSign(util.OrderParam(resp, "&key="+sandboxKey), util.SignTypeMD5, sandboxKey)
+		assert.Nil(t, err)
+		_, _ = w.Write(util.MapToXML(resp))
+	}))
+	defer server.Close()
+	old := baseURL
+	baseURL = server.URL
+	defer func() { baseURL = old }()
+
+	p := NewPay(&context.Context{
+		AppID:      "wx2421b1c4370ec43b",
+		PayMchID:   "10000101",
+		PayKey:     testPayKey,
+		PaySandbox: true,
+	})
+	_, err := p.UnifiedOrder(&Order{TradeType: TradeTypeNative, Body: "沙箱用例", OutTradeNo: "sandbox-1", TotalFee: 551, ProductID: "1"})
+	assert.Nil(t, err)
+
+	// 仿真测试系统退款无需证书
+	_, err = p.Refund(&Refund{OutTradeNo: "sandbox-1", OutRefundNo: "sandbox-1-1", TotalFee: 552, RefundFee: 551})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, signKeys, "沙箱密钥仅获取一次")
+
+	key, err := p.SignKey()
+	assert.Nil(t, err)
+	assert.Equal(t, sandboxKey, key)
+
+	// 共用缓存时，同一商户号更换支付 key 后不得复用旧 key 换取的沙箱密钥
+	c := cache.NewMemory()
+	assert.Nil(t, c.Set(cache.KeyPaySandboxSignKey("10000101", "old key"), "stale", time.Hour))
+	p = NewPay(&context.Context{PayMchID: "10000101", PayKey: testPayKey, PaySandbox: true, Cache: c})
+	key, err = p.SandboxSignKey()
+	assert.Nil(t, err)
+	assert.Equal(t, sandboxKey, key)
+	assert.Equal(t, 2, signKeys)
+}
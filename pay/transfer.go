@@ -0,0 +1,147 @@
+package pay
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gotid/god/lib/gconv"
+)
+
+const (
+	pathTransfers       = "/mmpaymkttransfers/promotion/transfers"
+	pathGetTransferInfo = "/mmpaymkttransfers/gettransferinfo"
+)
+
+// CheckName 企业付款的收款用户姓名校验选项
+type CheckName string
+
+const (
+	CheckNameNone  CheckName = "NO_CHECK"    // 不校验真实姓名
+	CheckNameForce CheckName = "FORCE_CHECK" // 强校验真实姓名
+)
+
+// TransferStatus 企业付款状态
+type TransferStatus string
+
+const (
+	TransferSuccess    TransferStatus = "SUCCESS"    // 转账成功
+	TransferFailed     TransferStatus = "FAILED"     // 转账失败
+	TransferProcessing TransferStatus = "PROCESSING" // 处理中
+)
+
+type (
+	// Transfer 企业付款至零钱参数
+	// https://pay.weixin.qq.com/wiki/doc/api/tools/mch_pay.php?chapter=14_2
+	Transfer struct {
+		PartnerTradeNo string    // 商户订单号，必填，结果不确定时须以原单号重试
+		OpenID         string    // 收款用户在 AppID 下的 openid，必填
+		CheckName      CheckName // 姓名校验选项，默认不校验
+		ReUserName     string    // 收款用户真实姓名，强校验时必填
+		Amount         int64     // 付款金额，单位分，必填
+		Desc           string    // 付款备注，必填
+		SpbillCreateIP string    // 调用接口的机器 IP
+		DeviceInfo     string    // 设备号
+	}
+
+	// TransferResult 企业付款结果
+	TransferResult struct {
+		PartnerTradeNo string // 商户订单号
+		PaymentNo      string // 微信付款单号
+		PaymentTime    string // 付款成功时间
+	}
+
+	// TransferInfo 企业付款查询结果
+	TransferInfo struct {
+		PartnerTradeNo string         // 商户订单号
+		DetailID       string         // 微信付款单号
+		Status         TransferStatus // 转账状态
+		Reason         string         // 失败原因
+		OpenID         string         // 收款用户 openid
+		TransferName   string         // 收款用户姓名
+		PaymentAmount  int64          // 付款金额，单位分
+		TransferTime   string         // 发起转账的时间
+		PaymentTime    string         // 付款成功时间
+		Desc           string         // 付款备注
+	}
+)
+
+// Transfer 企业付款至用户零钱，需要商户证书。
+// 同一商户订单号重复请求时微信不会重复付款；结果不确定时以原单号查询，已付款则视为成功，
+// 否则返回原错误，调用方应以原单号重试，切勿更换单号。
+func (p *Pay) Transfer(t *Transfer) (*TransferResult, error) {
+	if t.PartnerTradeNo == "" {
+		return nil, errors.New("企业付款缺少商户订单号")
+	}
+	if t.Amount <= 0 {
+		return nil, fmt.Errorf("企业付款 %s 的金额 %d 须大于 0", t.PartnerTradeNo, t.Amount)
+	}
+	if t.CheckName == "" {
+		t.CheckName = CheckNameNone
+	}
+	if t.CheckName == CheckNameForce && t.ReUserName == "" {
+		return nil, fmt.Errorf("企业付款 %s 强校验姓名时缺少收款用户姓名", t.PartnerTradeNo)
+	}
+
+	m, err := p.postWithCertUnsigned(pathTransfers, map[string]string{
+		"mch_appid":        p.AppID,
+		"mchid":            p.PayMchID,
+		"device_info":      t.DeviceInfo,
+		"partner_trade_no": t.PartnerTradeNo,
+		"openid":           t.OpenID,
+		"check_name":       string(t.CheckName),
+		"re_user_name":     t.ReUserName,
+		"amount":           strconv.FormatInt(t.Amount, 10),
+		"desc":             t.Desc,
+		"spbill_create_ip": t.SpbillCreateIP,
+	})
+	if err != nil {
+		if !transferUncertain(err) {
+			return nil, err
+		}
+		info, qerr := p.QueryTransfer(t.PartnerTradeNo)
+		if qerr != nil || info.Status != TransferSuccess {
+			return nil, err
+		}
+		return &TransferResult{
+			PartnerTradeNo: info.PartnerTradeNo,
+			PaymentNo:      info.DetailID,
+			PaymentTime:    info.PaymentTime,
+		}, nil
+	}
+
+	return &TransferResult{
+		PartnerTradeNo: m["partner_trade_no"],
+		PaymentNo:      m["payment_no"],
+		PaymentTime:    m["payment_time"],
+	}, nil
+}
+
+// QueryTransfer 按商户订单号查询企业付款，需要商户证书。
+func (p *Pay) QueryTransfer(partnerTradeNo string) (*TransferInfo, error) {
+	m, err := p.postWithCertUnsigned(pathGetTransferInfo, map[string]string{
+		"partner_trade_no": partnerTradeNo,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransferInfo{
+		PartnerTradeNo: m["partner_trade_no"],
+		DetailID:       m["detail_id"],
+		Status:         TransferStatus(m["status"]),
+		Reason:         m["reason"],
+		OpenID:         m["openid"],
+		TransferName:   m["transfer_name"],
+		PaymentAmount:  gconv.Int64(m["payment_amount"]),
+		TransferTime:   m["transfer_time"],
+		PaymentTime:    m["payment_time"],
+		Desc:           m["desc"],
+	}, nil
+}
+
+// 企业付款结果是否不确定：系统繁忙或非业务错误（如网络超时）
+func transferUncertain(err error) bool {
+	var e *Error
+	return errors.Is(err, ErrSystemError) || !errors.As(err, &e)
+}
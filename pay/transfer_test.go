@@ -0,0 +1,79 @@
+package pay
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPay_Transfer(t *testing.T) {
+	var transfers int
+	defer mockServer(t, func(path string, req map[string]string) map[string]string {
+		switch path {
+		case pathTransfers:
+			transfers++
+			assert.Equal(t, "wx2421b1c4370ec43b", req["mch_appid"])
+			assert.Equal(t, "10000100", req["mchid"])
+			assert.Empty(t, req["appid"])
+			assert.Equal(t, "NO_CHECK", req["check_name"])
+
+			switch req["partner_trade_no"] {
+			case "10000098201411111234567890":
+				return map[string]string{
+					"sign":             "",
+					"return_code":      "SUCCESS",
+					"result_code":      "SUCCESS",
+					"partner_trade_no": req["partner_trade_no"],
+					"payment_no":       "1000018301201505190181489473",
+					"payment_time":     "2015-05-19 15:26:59",
+				}
+			case "10000098201411111234567891":
+				return map[string]string{"sign": "", "return_code": "SUCCESS", "result_code": "FAIL", "err_code": "NOTENOUGH", "err_code_des": "余额不足"}
+			default:
+				return map[string]string{"sign": "", "return_code": "SUCCESS", "result_code": "FAIL", "err_code": "SYSTEMERROR", "err_code_des": "系统繁忙，请稍后再试。"}
+			}
+		case pathGetTransferInfo:
+			assert.Equal(t, "wx2421b1c4370ec43b", req["appid"])
+			return map[string]string{
+				"sign":             "",
+				"return_code":      "SUCCESS",
+				"result_code":      "SUCCESS",
+				"partner_trade_no": req["partner_trade_no"],
+				"detail_id":        "1000000000201503283103439304",
+				"status":           "SUCCESS",
+				"payment_amount":   "5000",
+				"payment_time":     "2015-04-21 20:00:00",
+			}
+		default:
+			t.Fatalf("未知的接口 %s", path)
+			return nil
+		}
+	})()
+
+	p := newTestPay()
+	var err error
+	p.P12, err = ioutil.ReadFile("testdata/apiclient_cert.p12")
+	assert.Nil(t, err)
+
+	transfer := &Transfer{PartnerTradeNo: "10000098201411111234567890", OpenID: "oxTWIuGaIt6gTKsQRLau2M0yL16E", Amount: 5000, Desc: "分销佣金"}
+	result, err := p.Transfer(transfer)
+	assert.Nil(t, err)
+	assert.Equal(t, "1000018301201505190181489473", result.PaymentNo)
+
+	transfer.PartnerTradeNo = "10000098201411111234567891"
+	_, err = p.Transfer(transfer)
+	assert.True(t, errors.Is(err, ErrNotEnough))
+	assert.False(t, errors.Is(err, ErrNameMismatch))
+
+	// 结果不确定时以原单号查询确认
+	transfer.PartnerTradeNo = "10000098201411111234567892"
+	result, err = p.Transfer(transfer)
+	assert.Nil(t, err)
+	assert.Equal(t, "1000000000201503283103439304", result.PaymentNo)
+	assert.Equal(t, 3, transfers)
+
+	_, err = p.Transfer(&Transfer{PartnerTradeNo: "10000098201411111234567893", Amount: 1, CheckName: CheckNameForce})
+	assert.NotNil(t, err, "强校验姓名时须提供姓名")
+}
@@ -0,0 +1,219 @@
+package pay
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gotid/god/lib/grand"
+	"github.com/gotid/wechat/util"
+)
+
+const pathUnifiedOrder = "/pay/unifiedorder"
+
+// TradeType 交易类型
+type TradeType string
+
+const (
+	TradeTypeJSAPI  TradeType = "JSAPI"  // 公众号、小程序支付
+	TradeTypeNative TradeType = "NATIVE" // 扫码支付
+	TradeTypeApp    TradeType = "APP"    // APP 支付
+	TradeTypeMWeb   TradeType = "MWEB"   // H5 支付
+)
+
+type (
+	// Order 统一下单参数
+	// https://pay.weixin.qq.com/wiki/doc/api/jsapi.php?chapter=9_1
+	Order struct {
+		TradeType      TradeType // 交易类型，必填
+		Body           string    // 商品描述，必填
+		OutTradeNo     string    // 商户订单号，必填
+		TotalFee       int64     // 订单总金额，单位分，必填
+		SpbillCreateIP string    // 终端IP，必填
+		NotifyURL      string    // 通知地址，默认为上下文中的 PayNotifyURL
+		OpenID         string    // 用户在服务商或普通商户应用下的标识，JSAPI 时与 SubOpenID 二选一
+		SubOpenID      string    // 服务商模式下用户在子商户应用下的标识
+		ProductID      string    // 商品ID，NATIVE 必填
+		SceneInfo      string    // 场景信息，MWEB 必填
+		DeviceInfo     string    // 设备号
+		Detail         string    // 商品详情
+		Attach         string    // 附加数据，在支付通知中原样返回
+		FeeType        string    // 标价币种，默认 CNY
+		TimeStart      string    // 交易起始时间 yyyyMMddHHmmss
+		TimeExpire     string    // 交易结束时间 yyyyMMddHHmmss
+		GoodsTag       string    // 订单优惠标记
+		LimitPay       string    // 指定支付方式，no_credit 为不能使用信用卡
+		Receipt        string    // 电子发票入口开放标识，Y 为开启
+		ProfitSharing  bool      // 是否需要分账
+		SignType       string    // 签名类型，默认 MD5
+	}
+
+	// OrderResult 统一下单结果
+	OrderResult struct {
+		TradeType TradeType // 交易类型
+		PrepayID  string    // 预支付交易会话标识
+		CodeURL   string    // 二维码链接，NATIVE 时返回
+		MWebURL   string    // 支付跳转链接，MWEB 时返回
+	}
+
+	// JSAPIParams 公众号内 WeixinJSBridge 调起支付的参数
+	JSAPIParams struct {
+		AppID     string `json:"appId"`
+		TimeStamp string `json:"timeStamp"`
+		NonceStr  string `json:"nonceStr"`
+		Package   string `json:"package"`
+		SignType  string `json:"signType"`
+		PaySign   string `json:"paySign"`
+	}
+
+	// MiniProgramParams 小程序 wx.requestPayment 调起支付的参数
+	MiniProgramParams struct {
+		TimeStamp string `json:"timeStamp"`
+		NonceStr  string `json:"nonceStr"`
+		Package   string `json:"package"`
+		SignType  string `json:"signType"`
+		PaySign   string `json:"paySign"`
+	}
+
+	// AppParams APP 调起支付的参数
+	AppParams struct {
+		AppID     string `json:"appid"`
+		PartnerID string `json:"partnerid"`
+		PrepayID  string `json:"prepayid"`
+		Package   string `json:"package"`
+		NonceStr  string `json:"noncestr"`
+		TimeStamp string `json:"timestamp"`
+		Sign      string `json:"sign"`
+	}
+)
+
+// UnifiedOrder 统一下单，返回预支付交易会话标识及扫码、H5 支付链接。
+func (p *Pay) UnifiedOrder(o *Order) (*OrderResult, error) {
+	params := map[string]string{
+		"trade_type":       string(o.TradeType),
+		"body":             o.Body,
+		"out_trade_no":     o.OutTradeNo,
+		"total_fee":        strconv.FormatInt(o.TotalFee, 10),
+		"spbill_create_ip": o.SpbillCreateIP,
+		"notify_url":       o.NotifyURL,
+		"openid":           o.OpenID,
+		"sub_openid":       o.SubOpenID,
+		"product_id":       o.ProductID,
+		"scene_info":       o.SceneInfo,
+		"device_info":      o.DeviceInfo,
+		"detail":           o.Detail,
+		"attach":           o.Attach,
+		"fee_type":         o.FeeType,
+		"time_start":       o.TimeStart,
+		"time_expire":      o.TimeExpire,
+		"goods_tag":        o.GoodsTag,
+		"limit_pay":        o.LimitPay,
+		"receipt":          o.Receipt,
+		"sign_type":        o.SignType,
+	}
+	if params["notify_url"] == "" {
+		params["notify_url"] = p.PayNotifyURL
+	}
+	if o.ProfitSharing {
+		params["profit_sharing"] = "Y"
+	}
+
+	m, err := p.post(pathUnifiedOrder, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrderResult{
+		TradeType: TradeType(m["trade_type"]),
+		PrepayID:  m["prepay_id"],
+		CodeURL:   m["code_url"],
+		MWebURL:   m["mweb_url"],
+	}, nil
+}
+
+// JSAPIParams 返回公众号内调起支付的参数，服务商模式下指定了子商户应用ID时由子商户应用调起
+func (p *Pay) JSAPIParams(prepayID string) (*JSAPIParams, error) {
+	params := map[string]string{
+		"appId":     p.payAppID(),
+		"timeStamp": strconv.FormatInt(time.Now().Unix(), 10),
+		"nonceStr":  grand.S(32),
+		"package":   "prepay_id=" + prepayID,
+		"signType":  util.SignTypeMD5,
+	}
+
+	key, err := p.SignKey()
+	if err != nil {
+		return nil, err
+	}
+	sign, err := util.ParamSign(params, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSAPIParams{
+		AppID:     params["appId"],
+		TimeStamp: params["timeStamp"],
+		NonceStr:  params["nonceStr"],
+		Package:   params["package"],
+		SignType:  params["signType"],
+		PaySign:   sign,
+	}, nil
+}
+
+// MiniProgramParams 返回小程序调起支付的参数
+func (p *Pay) MiniProgramParams(prepayID string) (*MiniProgramParams, error) {
+	params, err := p.JSAPIParams(prepayID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MiniProgramParams{
+		TimeStamp: params.TimeStamp,
+		NonceStr:  params.NonceStr,
+		Package:   params.Package,
+		SignType:  params.SignType,
+		PaySign:   params.PaySign,
+	}, nil
+}
+
+// AppParams 返回 APP 调起支付的参数，服务商模式下由子商户应用调起
+func (p *Pay) AppParams(prepayID string) (*AppParams, error) {
+	partnerID := p.PayMchID
+	if p.subMchID != "" {
+		partnerID = p.subMchID
+	}
+	params := map[string]string{
+		"appid":     p.payAppID(),
+		"partnerid": partnerID,
+		"prepayid":  prepayID,
+		"package":   "Sign=WXPay",
+		"noncestr":  grand.S(32),
+		"timestamp": strconv.FormatInt(time.Now().Unix(), 10),
+	}
+
+	key, err := p.SignKey()
+	if err != nil {
+		return nil, err
+	}
+	sign, err := util.ParamSign(params, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AppParams{
+		AppID:     params["appid"],
+		PartnerID: params["partnerid"],
+		PrepayID:  params["prepayid"],
+		Package:   params["package"],
+		NonceStr:  params["noncestr"],
+		TimeStamp: params["timestamp"],
+		Sign:      sign,
+	}, nil
+}
+
+// 返回调起支付的应用ID
+func (p *Pay) payAppID() string {
+	if p.subAppID != "" {
+		return p.subAppID
+	}
+	return p.AppID
+}
@@ -0,0 +1,307 @@
+package payv3
+
+import (
+	"crypto/md5"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gotid/god/lib/gconv"
+	"github.com/gotid/god/lib/logx"
+	"github.com/gotid/god/lib/syncx"
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/util"
+)
+
+const (
+	pathCertificates = "/v3/certificates"
+
+	// 平台证书的定期更新间隔
+	certificateRefreshInterval = 12 * time.Hour
+	// 最新的平台证书过期前提前更新的时长
+	certificateRefreshAdvance = 24 * time.Hour
+	// 更新失败后的重试间隔
+	certificateRetryInterval = time.Minute
+	// 未知序列号强制更新的最小间隔，避免伪造的序列号频繁触发下载
+	certificateForceInterval = time.Minute
+)
+
+// 各商户共享的平台证书管理器，以商户号、商户证书序列号和 APIv3 密钥摘要为键，
+// 商户更换 API 证书或 APIv3 密钥后使用新的管理器，不会以旧的签名材料下载证书
+var certificateManagers sync.Map
+
+// CertificateProvider 微信支付平台证书提供者
+type CertificateProvider interface {
+	// Certificate 返回指定序列号的平台证书
+	Certificate(serial string) (*x509.Certificate, error)
+}
+
+// Certificates 固定的平台证书集合，以证书序列号为键
+type Certificates map[string]*x509.Certificate
+
+// 可提供最新平台证书的证书提供者，用于加密请求中的敏感信息
+type latestCertificateProvider interface {
+	// LatestCertificate 返回有效期最晚的平台证书及其序列号
+	LatestCertificate() (string, *x509.Certificate, error)
+}
+
+// LatestCertificate 返回有效期最晚的平台证书及其序列号
+func (c Certificates) LatestCertificate() (string, *x509.Certificate, error) {
+	var (
+		serial string
+		latest *x509.Certificate
+	)
+	for k, cert := range c {
+		if latest == nil || cert.NotAfter.After(latest.NotAfter) {
+			serial, latest = k, cert
+		}
+	}
+	if latest == nil || time.Now().After(latest.NotAfter) {
+		return "", nil, errors.New("没有有效的平台证书")
+	}
+	return serial, latest, nil
+}
+
+// Certificate 返回指定序列号的平台证书
+func (c Certificates) Certificate(serial string) (*x509.Certificate, error) {
+	cert, ok := c[serial]
+	if !ok {
+		return nil, fmt.Errorf("平台证书 %s 不存在", serial)
+	}
+	return cert, nil
+}
+
+// CertificateManager 平台证书管理器：下载并解密平台证书，按序列号缓存至上下文缓存，
+// 在定期更新时间或最新证书过期前自动更新，可在协程间共享。
+type CertificateManager struct {
+	pay       *Pay
+	flight    syncx.SingleFlight
+	lock      sync.RWMutex
+	certs     Certificates
+	refreshAt time.Time
+	forcedAt  time.Time // 上次因未知序列号强制下载的时间
+}
+
+// NewCertificateManager 返回一个新的平台证书管理器
+func NewCertificateManager(ctx *context.Context) *CertificateManager {
+	m := &CertificateManager{
+		flight: syncx.NewSingleFlight(),
+		certs:  make(Certificates),
+	}
+	m.pay = &Pay{Context: ctx, certs: m}
+	return m
+}
+
+// 返回商户共享的平台证书管理器
+func sharedCertificateManager(ctx *context.Context) *CertificateManager {
+	key := fmt.Sprintf("%s:%s:%x", ctx.PayMchID, ctx.PaySerialNo, md5.Sum([]byte(ctx.PayAPIv3Key)))
+	if m, ok := certificateManagers.Load(key); ok {
+		return m.(*CertificateManager)
+	}
+
+	m, _ := certificateManagers.LoadOrStore(key, NewCertificateManager(ctx))
+	return m.(*CertificateManager)
+}
+
+// Certificate 返回指定序列号的平台证书，本地和缓存中均不存在时下载平台证书。
+// 回调验签前即会查询证书，因此未知序列号触发的下载在最小间隔内至多一次。
+func (m *CertificateManager) Certificate(serial string) (*x509.Certificate, error) {
+	if m.due() {
+		if err := m.Refresh(); err != nil {
+			logx.Errorf("更新微信支付平台证书失败：mchid=%s, err=%v", m.pay.PayMchID, err)
+		}
+	}
+
+	if cert := m.load(serial); cert != nil {
+		return cert, nil
+	}
+	if cert := m.loadCache(serial); cert != nil {
+		return cert, nil
+	}
+
+	if err := m.forceRefresh(); err != nil {
+		return nil, err
+	}
+	if cert := m.load(serial); cert != nil {
+		return cert, nil
+	}
+	return nil, fmt.Errorf("平台证书 %s 不存在", serial)
+}
+
+// LatestCertificate 返回有效期最晚的平台证书及其序列号，本地没有证书或已到更新时间时下载平台证书
+func (m *CertificateManager) LatestCertificate() (string, *x509.Certificate, error) {
+	m.lock.RLock()
+	empty := len(m.certs) == 0
+	m.lock.RUnlock()
+	if empty || m.due() {
+		if err := m.Refresh(); err != nil && empty {
+			return "", nil, err
+		}
+	}
+
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.certs.LatestCertificate()
+}
+
+// Refresh 下载并更新平台证书，并发调用时仅下载一次
+func (m *CertificateManager) Refresh() error {
+	_, _, err := m.flight.Do(m.pay.PayMchID, func() (interface{}, error) {
+		return nil, m.refreshOrRetry()
+	})
+	return err
+}
+
+// 因未知序列号强制更新平台证书，距上次强制下载不足最小间隔时不下载
+func (m *CertificateManager) forceRefresh() error {
+	_, _, err := m.flight.Do(m.pay.PayMchID, func() (interface{}, error) {
+		m.lock.Lock()
+		if time.Since(m.forcedAt) < certificateForceInterval {
+			m.lock.Unlock()
+			return nil, nil
+		}
+		m.forcedAt = time.Now()
+		m.lock.Unlock()
+
+		return nil, m.refreshOrRetry()
+	})
+	return err
+}
+
+// 下载并更新平台证书，失败时稍后重试
+func (m *CertificateManager) refreshOrRetry() error {
+	err := m.refresh()
+	if err != nil {
+		m.lock.Lock()
+		m.refreshAt = time.Now().Add(certificateRetryInterval)
+		m.lock.Unlock()
+	}
+	return err
+}
+
+func (m *CertificateManager) refresh() error {
+	header, body, err := m.pay.request(http.MethodGet, pathCertificates, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Data []struct {
+			SerialNo           string   `json:"serial_no"`
+			EffectiveTime      string   `json:"effective_time"`
+			ExpireTime         string   `json:"expire_time"`
+			EncryptCertificate Resource `json:"encrypt_certificate"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("解析平台证书失败：%w", err)
+	}
+
+	certs := make(Certificates, len(resp.Data))
+	pems := make(map[string][]byte, len(resp.Data))
+	for _, d := range resp.Data {
+		data, err := m.pay.DecryptResource(&d.EncryptCertificate)
+		if err != nil {
+			return fmt.Errorf("解密平台证书 %s 失败：%w", d.SerialNo, err)
+		}
+		cert, err := util.ParseCertificate(data)
+		if err != nil {
+			return fmt.Errorf("解析平台证书 %s 失败：%w", d.SerialNo, err)
+		}
+		certs[d.SerialNo] = cert
+		pems[d.SerialNo] = data
+	}
+
+	// 平台证书应答由其中的证书签名
+	verifier := &Pay{Context: m.pay.Context, certs: certs}
+	if err := verifier.VerifySignature(header, body); err != nil {
+		return fmt.Errorf("平台证书应答验签失败：%w", err)
+	}
+
+	if m.pay.Cache != nil {
+		for serial, cert := range certs {
+			if err := m.pay.Cache.Set(cache.KeyPayCertificate(m.pay.PayMchID, serial), string(pems[serial]), time.Until(cert.NotAfter)); err != nil {
+				logx.Errorf("缓存微信支付平台证书失败：mchid=%s, serial=%s, err=%v", m.pay.PayMchID, serial, err)
+			}
+		}
+	}
+
+	m.lock.Lock()
+	m.certs = certs
+	m.refreshAt = nextRefreshTime(certs)
+	m.lock.Unlock()
+
+	return nil
+}
+
+// 是否已到更新时间，首次使用时优先读取缓存
+func (m *CertificateManager) due() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return !m.refreshAt.IsZero() && time.Now().After(m.refreshAt)
+}
+
+// 从本地读取未过期的平台证书
+func (m *CertificateManager) load(serial string) *x509.Certificate {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	cert, ok := m.certs[serial]
+	if !ok || time.Now().After(cert.NotAfter) {
+		return nil
+	}
+	return cert
+}
+
+// 从上下文缓存读取平台证书，并保存至本地
+func (m *CertificateManager) loadCache(serial string) *x509.Certificate {
+	if m.pay.Cache == nil {
+		return nil
+	}
+	v := m.pay.Cache.Get(cache.KeyPayCertificate(m.pay.PayMchID, serial))
+	if v == nil {
+		return nil
+	}
+
+	cert, err := util.ParseCertificate([]byte(gconv.String(v)))
+	if err != nil || time.Now().After(cert.NotAfter) {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	certs := make(Certificates, len(m.certs)+1)
+	for k, c := range m.certs {
+		certs[k] = c
+	}
+	certs[serial] = cert
+	m.certs = certs
+	if m.refreshAt.IsZero() {
+		m.refreshAt = nextRefreshTime(certs)
+	}
+	return cert
+}
+
+// 计算下次更新时间：定期更新，且不晚于最新证书过期前的提前量
+func nextRefreshTime(certs Certificates) time.Time {
+	next := time.Now().Add(certificateRefreshInterval)
+
+	var latest time.Time
+	for _, cert := range certs {
+		if cert.NotAfter.After(latest) {
+			latest = cert.NotAfter
+		}
+	}
+	if advance := latest.Add(-certificateRefreshAdvance); !latest.IsZero() && advance.Before(next) {
+		next = advance
+	}
+	if earliest := time.Now().Add(certificateRetryInterval); next.Before(earliest) {
+		next = earliest
+	}
+	return next
+}
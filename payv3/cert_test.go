@@ -0,0 +1,141 @@
+package payv3
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/context"
+	"github.com/stretchr/testify/assert"
+)
+
+// 以 APIv3 密钥加密数据
+func encryptResource(t *testing.T, plaintext []byte) Resource {
+	block, err := aes.NewCipher([]byte(testAPIv3Key))
+	assert.Nil(t, err)
+	gcm, err := cipher.NewGCM(block)
+	assert.Nil(t, err)
+
+	nonce, associatedData := "27ad2d5d8e82", "certificate"
+	return Resource{
+		Algorithm:      algorithmAESGCM,
+		Ciphertext:     base64.StdEncoding.EncodeToString(gcm.Seal(nil, []byte(nonce), plaintext, []byte(associatedData))),
+		AssociatedData: associatedData,
+		Nonce:          nonce,
+	}
+}
+
+func TestCertificateManager(t *testing.T) {
+	var downloads int32
+	defer mockServer(t, func(r *http.Request, body []byte) (int, interface{}) {
+		if r.URL.Path != pathCertificates {
+			return http.StatusOK, map[string]string{"status": "SUCCESS"}
+		}
+		atomic.AddInt32(&downloads, 1)
+
+		return http.StatusOK, map[string]interface{}{
+			"data": []interface{}{map[string]interface{}{
+				"serial_no":           testPlatformSerial,
+				"encrypt_certificate": encryptResource(t, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testPlatform.Raw})),
+			}},
+		}
+	})()
+
+	ctx := newTestPay().Context
+	ctx.Cache = cache.NewMemory()
+	m := NewCertificateManager(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cert, err := m.Certificate(testPlatformSerial)
+			assert.Nil(t, err)
+			assert.Equal(t, testPlatform.Raw, cert.Raw)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads), "并发获取时仅下载一次")
+
+	// 新的管理器优先读取缓存的平台证书
+	cert, err := NewCertificateManager(ctx).Certificate(testPlatformSerial)
+	assert.Nil(t, err)
+	assert.Equal(t, testPlatform.Raw, cert.Raw)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+
+	// 未知的证书序列号在强制更新间隔内不再下载
+	_, err = m.Certificate("UNKNOWN")
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+
+	// 超过强制更新间隔后，未知的证书序列号触发重新下载
+	m.lock.Lock()
+	m.forcedAt = time.Now().Add(-certificateForceInterval)
+	m.lock.Unlock()
+	_, err = m.Certificate("UNKNOWN")
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&downloads))
+
+	// 使用管理器校验应答签名
+	result, err := NewPay(ctx, m).QueryRefund("1217752501201407033233368018-1")
+	assert.Nil(t, err)
+	assert.Equal(t, RefundStatusSuccess, result.Status)
+}
+
+func TestCertificateManager_ForgedSerials(t *testing.T) {
+	var downloads int32
+	defer mockServer(t, func(r *http.Request, body []byte) (int, interface{}) {
+		atomic.AddInt32(&downloads, 1)
+		return http.StatusOK, map[string]interface{}{
+			"data": []interface{}{map[string]interface{}{
+				"serial_no":           testPlatformSerial,
+				"encrypt_certificate": encryptResource(t, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testPlatform.Raw})),
+			}},
+		}
+	})()
+
+	m := NewCertificateManager(newTestPay().Context)
+
+	// 伪造序列号的回调并发或先后到达，至多触发一次下载
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := m.Certificate(fmt.Sprintf("FORGED%d", i))
+			assert.NotNil(t, err)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 20; i++ {
+		_, err := m.Certificate(fmt.Sprintf("FORGED-AGAIN%d", i))
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&downloads))
+
+	// 已下载的证书仍可正常获取
+	cert, err := m.Certificate(testPlatformSerial)
+	assert.Nil(t, err)
+	assert.Equal(t, testPlatform.Raw, cert.Raw)
+}
+
+func TestSharedCertificateManager(t *testing.T) {
+	ctx := &context.Context{PayMchID: "1900009191", PayAPIv3Key: testAPIv3Key, PaySerialNo: "1DDE55AD98ED71D6EDD4A4A16996DE7B47773A8C"}
+	m := sharedCertificateManager(ctx)
+	assert.Same(t, m, sharedCertificateManager(&context.Context{PayMchID: ctx.PayMchID, PayAPIv3Key: ctx.PayAPIv3Key, PaySerialNo: ctx.PaySerialNo}))
+
+	// 更换商户 API 证书后不得沿用以旧证书签名的管理器
+	rotated := *ctx
+	rotated.PaySerialNo = "5157F09EFDC096DE15EBE81A47057A7232F1B8E1"
+	assert.NotSame(t, m, sharedCertificateManager(&rotated))
+	assert.Equal(t, rotated.PaySerialNo, sharedCertificateManager(&rotated).pay.PaySerialNo)
+}
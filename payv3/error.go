@@ -0,0 +1,24 @@
+package payv3
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Error 微信支付 APIv3 错误应答
+type Error struct {
+	StatusCode int             `json:"-"`       // HTTP 状态码
+	Code       string          `json:"code"`    // 详细错误码
+	Message    string          `json:"message"` // 错误描述
+	Detail     json.RawMessage `json:"detail"`  // 错误详情
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("微信支付请求失败：status=%d, code=%s, message=%s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is 错误码相同时视为同一错误
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
@@ -0,0 +1,205 @@
+// Package payv3 提供微信支付 APIv3 商户接口。
+// 请求以商户 API 私钥进行 SHA256-RSA 签名，响应以微信支付平台证书验签。
+package payv3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gotid/god/lib/grand"
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/util"
+)
+
+// 微信支付接口地址
+var baseURL = "https://api.mch.weixin.qq.com"
+
+const (
+	authorizationSchema = "WECHATPAY2-SHA256-RSA2048"
+
+	headerTimestamp = "Wechatpay-Timestamp"
+	headerNonce     = "Wechatpay-Nonce"
+	headerSignature = "Wechatpay-Signature"
+	headerSerial    = "Wechatpay-Serial"
+
+	// 应答和回调时间戳与本地时间的最大偏差
+	maxTimestampSkew = 5 * time.Minute
+)
+
+// 已解析的商户私钥，以私钥摘要为键
+var privateKeys sync.Map
+
+// Pay 微信支付 APIv3 控制器
+type Pay struct {
+	*context.Context
+	certs CertificateProvider
+}
+
+// NewPay 返回一个新的微信支付 APIv3 控制器，平台证书用于校验应答和回调签名。
+// 未指定平台证书时，使用该商户共享的平台证书管理器自动下载和更新。
+func NewPay(ctx *context.Context, certs CertificateProvider) *Pay {
+	if certs == nil {
+		certs = sharedCertificateManager(ctx)
+	}
+	return &Pay{Context: ctx, certs: certs}
+}
+
+// 发起 APIv3 请求：签名请求，校验应答签名，并将应答解析至 result
+func (p *Pay) do(method, path string, body, result interface{}) error {
+	return p.doWithHeader(method, path, nil, body, result)
+}
+
+// 携带额外的请求头发起 APIv3 请求，如加密敏感信息时的平台证书序列号
+func (p *Pay) doWithHeader(method, path string, extra http.Header, body, result interface{}) error {
+	header, data, err := p.request(method, path, extra, body)
+	if err != nil {
+		return err
+	}
+
+	if err := p.VerifySignature(header, data); err != nil {
+		return fmt.Errorf("微信支付应答验签失败：path=%s, err=%w", path, err)
+	}
+
+	if result == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+// 发起签名的 APIv3 请求，返回未验签的应答头和应答数据
+func (p *Pay) request(method, path string, extra http.Header, body interface{}) (http.Header, []byte, error) {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	req, err := http.NewRequest(method, baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	authorization, err := p.authorization(method, req.URL.RequestURI(), data)
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range extra {
+		req.Header[k] = v
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := util.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		e := &Error{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(respBody, e); err != nil {
+			e.Message = string(respBody)
+		}
+		return nil, nil, e
+	}
+
+	return resp.Header, respBody, nil
+}
+
+// 生成请求的 Authorization 头
+func (p *Pay) authorization(method, uri string, body []byte) (string, error) {
+	key, err := p.privateKey()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := grand.S(32)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := util.RSASignSHA256(key, buildMessage(method, uri, timestamp, nonce, string(body)))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`%s mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		authorizationSchema, p.PayMchID, nonce, signature, timestamp, p.PaySerialNo), nil
+}
+
+// VerifySignature 使用平台证书校验应答或回调的签名
+func (p *Pay) VerifySignature(header http.Header, body []byte) error {
+	timestamp := header.Get(headerTimestamp)
+	nonce := header.Get(headerNonce)
+	signature := header.Get(headerSignature)
+	serial := header.Get(headerSerial)
+	if timestamp == "" || nonce == "" || signature == "" || serial == "" {
+		return fmt.Errorf("缺少签名头 %s", headerSignature)
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的时间戳 %s", timestamp)
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > maxTimestampSkew || skew < -maxTimestampSkew {
+		return fmt.Errorf("时间戳 %s 已过期", timestamp)
+	}
+
+	if p.certs == nil {
+		return fmt.Errorf("商户 %s 未配置平台证书", p.PayMchID)
+	}
+	cert, err := p.certs.Certificate(serial)
+	if err != nil {
+		return err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("平台证书 %s 不是 RSA 证书", serial)
+	}
+
+	return util.RSAVerifySHA256(key, buildMessage(timestamp, nonce, string(body)), signature)
+}
+
+// 返回已解析的商户私钥
+func (p *Pay) privateKey() (*rsa.PrivateKey, error) {
+	if len(p.PayPrivateKey) == 0 {
+		return nil, fmt.Errorf("商户 %s 未配置 API 私钥", p.PayMchID)
+	}
+
+	digest := md5.Sum(p.PayPrivateKey)
+	if key, ok := privateKeys.Load(digest); ok {
+		return key.(*rsa.PrivateKey), nil
+	}
+
+	key, err := util.ParseRSAPrivateKey(p.PayPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("加载商户 %s 的 API 私钥失败：%w", p.PayMchID, err)
+	}
+	privateKeys.Store(digest, key)
+	return key, nil
+}
+
+// 构造签名串：每行一个字段，以换行符结尾
+func buildMessage(fields ...string) []byte {
+	var buf bytes.Buffer
+	for _, field := range fields {
+		buf.WriteString(field)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
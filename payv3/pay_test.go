@@ -0,0 +1,229 @@
+package payv3
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/pay"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testPlatformSerial = "5157F09EFDC096DE15EBE81A47057A7232F1B8E1"
+	testAPIv3Key       = "a7cde1ef7a2b4e5c8d9f0a1b2c3d4e5f"
+)
+
+var (
+	testMerchantKey = mustGenerateKey()
+	testPlatformKey = mustGenerateKey()
+	testPlatform    = mustCertificate(testPlatformKey)
+
+	authorizationPattern = regexp.MustCompile(`^WECHATPAY2-SHA256-RSA2048 mchid="(\w+)",nonce_str="(\w+)",signature="([^"]+)",timestamp="(\d+)",serial_no="(\w+)"$`)
+)
+
+func mustGenerateKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func mustCertificate(key *rsa.PrivateKey) *x509.Certificate {
+	serial, _ := new(big.Int).SetString(testPlatformSerial, 16)
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "Tenpay.com Root CA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return cert
+}
+
+func newTestPay() *Pay {
+	return NewPay(&context.Context{
+		AppID:              "wxd678efh567hg6787",
+		PayMchID:           "1230000109",
+		PayNotifyURL:       "https://example.com/notify",
+		PayRefundNotifyURL: "https://example.com/refund",
+		PayPrivateKey:      pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(testMerchantKey)}),
+		PaySerialNo:        "1DDE55AD98ED71D6EDD4A4A16996DE7B47773A8C",
+		PayAPIv3Key:        testAPIv3Key,
+	}, Certificates{testPlatformSerial: testPlatform})
+}
+
+// 签名应答并设置平台签名头
+func signResponse(w http.ResponseWriter, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := "fdasflkja484w"
+	signature, _ := util.RSASignSHA256(testPlatformKey, buildMessage(timestamp, nonce, string(body)))
+	w.Header().Set(headerTimestamp, timestamp)
+	w.Header().Set(headerNonce, nonce)
+	w.Header().Set(headerSignature, signature)
+	w.Header().Set(headerSerial, testPlatformSerial)
+}
+
+// 启动模拟的微信支付 APIv3 服务器，校验请求签名后返回平台签名的应答
+func mockServer(t *testing.T, handle func(r *http.Request, body []byte) (int, interface{})) func() {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+
+		matches := authorizationPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+		if assert.Len(t, matches, 6, "Authorization 格式错误") {
+			assert.Equal(t, "1230000109", matches[1])
+			message := buildMessage(r.Method, r.URL.RequestURI(), matches[4], matches[2], string(body))
+			assert.Nil(t, util.RSAVerifySHA256(&testMerchantKey.PublicKey, message, matches[3]), "请求签名不匹配")
+		}
+
+		status, result := handle(r, body)
+		var data []byte
+		if result != nil {
+			data, _ = json.Marshal(result)
+		}
+		signResponse(w, data)
+		w.WriteHeader(status)
+		_, _ = w.Write(data)
+	}))
+
+	old := baseURL
+	baseURL = server.URL
+	return func() {
+		baseURL = old
+		server.Close()
+	}
+}
+
+func TestPay_JSAPI(t *testing.T) {
+	defer mockServer(t, func(r *http.Request, body []byte) (int, interface{}) {
+		assert.Equal(t, pathTransactions+"jsapi", r.URL.Path)
+
+		var req Transaction
+		assert.Nil(t, json.Unmarshal(body, &req))
+		assert.Equal(t, "wxd678efh567hg6787", req.AppID)
+		assert.Equal(t, "1230000109", req.MchID)
+		assert.Equal(t, "https://example.com/notify", req.NotifyURL)
+		assert.Equal(t, int64(100), req.Amount.Total)
+
+		return http.StatusOK, map[string]string{"prepay_id": "wx26112221580621e9b071c00d9e093b0000"}
+	})()
+
+	p := newTestPay()
+	prepayID, err := p.JSAPI(&Transaction{
+		Description: "Image形象店-深圳腾大-QQ公仔",
+		OutTradeNo:  "1217752501201407033233368018",
+		Amount:      Amount{Total: 100},
+		Payer:       &Payer{OpenID: "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "wx26112221580621e9b071c00d9e093b0000", prepayID)
+
+	params, err := p.MiniProgramParams(prepayID)
+	assert.Nil(t, err)
+	assert.Equal(t, "RSA", params.SignType)
+	message := buildMessage(p.AppID, params.TimeStamp, params.NonceStr, params.Package)
+	assert.Nil(t, util.RSAVerifySHA256(&testMerchantKey.PublicKey, message, params.PaySign))
+}
+
+func TestPay_QueryAndClose(t *testing.T) {
+	defer mockServer(t, func(r *http.Request, body []byte) (int, interface{}) {
+		switch r.URL.Path {
+		case pathTransactionByOutTrade + "1217752501201407033233368018":
+			assert.Equal(t, "1230000109", r.URL.Query().Get("mchid"))
+			return http.StatusOK, map[string]interface{}{
+				"out_trade_no":   "1217752501201407033233368018",
+				"transaction_id": "1217752501201407033233368018",
+				"trade_type":     "JSAPI",
+				"trade_state":    "SUCCESS",
+				"amount":         map[string]interface{}{"total": 100, "payer_total": 100},
+			}
+		case pathTransactionByOutTrade + "1217752501201407033233368018/close":
+			assert.JSONEq(t, `{"mchid":"1230000109"}`, string(body))
+			return http.StatusNoContent, nil
+		default:
+			return http.StatusNotFound, map[string]string{"code": "ORDER_NOT_EXIST", "message": "订单不存在"}
+		}
+	})()
+
+	p := newTestPay()
+	result, err := p.QueryByOutTradeNo("1217752501201407033233368018")
+	assert.Nil(t, err)
+	assert.Equal(t, pay.TradeStateSuccess, result.TradeState)
+	assert.Equal(t, int64(100), result.Amount.PayerTotal)
+
+	assert.Nil(t, p.Close("1217752501201407033233368018"))
+
+	_, err = p.QueryByTransactionID("4200000000000000000000000000")
+	assert.Equal(t, "ORDER_NOT_EXIST", err.(*Error).Code)
+	assert.Equal(t, http.StatusNotFound, err.(*Error).StatusCode)
+}
+
+func TestPay_Refund(t *testing.T) {
+	defer mockServer(t, func(r *http.Request, body []byte) (int, interface{}) {
+		assert.Equal(t, pathRefunds, r.URL.Path)
+
+		var req Refund
+		assert.Nil(t, json.Unmarshal(body, &req))
+		assert.Equal(t, "https://example.com/refund", req.NotifyURL)
+		assert.Equal(t, "CNY", req.Amount.Currency)
+
+		return http.StatusOK, map[string]interface{}{
+			"refund_id":     "50000000382019052709732678859",
+			"out_refund_no": req.OutRefundNo,
+			"status":        "PROCESSING",
+			"amount":        map[string]interface{}{"refund": req.Amount.Refund, "total": req.Amount.Total},
+		}
+	})()
+
+	p := newTestPay()
+	result, err := p.Refund(&Refund{OutTradeNo: "1217752501201407033233368018", OutRefundNo: "1217752501201407033233368018-1", Amount: RefundAmount{Refund: 30, Total: 100}})
+	assert.Nil(t, err)
+	assert.Equal(t, RefundStatusProcessing, result.Status)
+	assert.Equal(t, int64(30), result.Amount.Refund)
+
+	_, err = p.Refund(&Refund{OutRefundNo: "1217752501201407033233368018-2", Amount: RefundAmount{Refund: 101, Total: 100}})
+	assert.NotNil(t, err, "退款金额不能超过订单金额")
+}
+
+func TestPay_VerifySignature(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signResponse(w, []byte(`{"prepay_id":"wx1"}`))
+		_, _ = w.Write([]byte(`{"prepay_id":"wx2"}`))
+	}))
+	defer server.Close()
+	old := baseURL
+	baseURL = server.URL
+	defer func() { baseURL = old }()
+
+	_, err := newTestPay().Native(&Transaction{Amount: Amount{Total: 1}})
+	assert.NotNil(t, err, "应答被篡改时验签失败")
+
+	header := http.Header{}
+	header.Set(headerTimestamp, strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	header.Set(headerNonce, "nonce")
+	header.Set(headerSignature, "c2lnbmF0dXJl")
+	header.Set(headerSerial, testPlatformSerial)
+	assert.NotNil(t, newTestPay().VerifySignature(header, nil), "时间戳过期时验签失败")
+}
@@ -0,0 +1,88 @@
+package payv3
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const pathRefunds = "/v3/refund/domestic/refunds"
+
+// RefundStatus 退款状态
+type RefundStatus string
+
+const (
+	RefundStatusSuccess    RefundStatus = "SUCCESS"    // 退款成功
+	RefundStatusClosed     RefundStatus = "CLOSED"     // 退款关闭
+	RefundStatusProcessing RefundStatus = "PROCESSING" // 退款处理中
+	RefundStatusAbnormal   RefundStatus = "ABNORMAL"   // 退款异常
+)
+
+type (
+	// Refund 申请退款参数，同一订单可分多次部分退款，每次使用不同的商户退款单号
+	// https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_5_9.shtml
+	Refund struct {
+		TransactionID string       `json:"transaction_id,omitempty"` // 微信支付订单号，与商户订单号二选一
+		OutTradeNo    string       `json:"out_trade_no,omitempty"`   // 商户订单号，与微信支付订单号二选一
+		OutRefundNo   string       `json:"out_refund_no"`            // 商户退款单号，必填
+		Reason        string       `json:"reason,omitempty"`         // 退款原因
+		NotifyURL     string       `json:"notify_url,omitempty"`     // 退款结果通知地址，默认为上下文中的 PayRefundNotifyURL
+		FundsAccount  string       `json:"funds_account,omitempty"`  // 退款资金来源
+		Amount        RefundAmount `json:"amount"`                   // 金额信息，必填
+	}
+
+	// RefundAmount 退款金额
+	RefundAmount struct {
+		Refund           int64  `json:"refund"`                      // 退款金额，单位分
+		Total            int64  `json:"total"`                       // 原订单金额，单位分
+		Currency         string `json:"currency"`                    // 退款币种
+		PayerTotal       int64  `json:"payer_total,omitempty"`       // 用户支付金额，仅结果返回
+		PayerRefund      int64  `json:"payer_refund,omitempty"`      // 用户退款金额，仅结果返回
+		SettlementRefund int64  `json:"settlement_refund,omitempty"` // 应结退款金额，仅结果返回
+		SettlementTotal  int64  `json:"settlement_total,omitempty"`  // 应结订单金额，仅结果返回
+		DiscountRefund   int64  `json:"discount_refund,omitempty"`   // 优惠退款金额，仅结果返回
+	}
+
+	// RefundResult 退款结果
+	RefundResult struct {
+		RefundID            string       `json:"refund_id"`             // 微信支付退款单号
+		OutRefundNo         string       `json:"out_refund_no"`         // 商户退款单号
+		TransactionID       string       `json:"transaction_id"`        // 微信支付订单号
+		OutTradeNo          string       `json:"out_trade_no"`          // 商户订单号
+		Channel             string       `json:"channel"`               // 退款渠道 ORIGINAL/BALANCE/OTHER_BALANCE/OTHER_BANKCARD
+		UserReceivedAccount string       `json:"user_received_account"` // 退款入账账户
+		SuccessTime         string       `json:"success_time"`          // 退款成功时间，RFC3339 格式
+		CreateTime          string       `json:"create_time"`           // 退款创建时间，RFC3339 格式
+		Status              RefundStatus `json:"status"`                // 退款状态
+		FundsAccount        string       `json:"funds_account"`         // 资金账户
+		Amount              RefundAmount `json:"amount"`                // 金额信息
+	}
+)
+
+// Refund 申请退款，退款结果需查询或等待退款结果通知
+func (p *Pay) Refund(r *Refund) (*RefundResult, error) {
+	if r.Amount.Refund <= 0 || r.Amount.Refund > r.Amount.Total {
+		return nil, fmt.Errorf("退款金额 %d 须大于 0 且不超过订单金额 %d", r.Amount.Refund, r.Amount.Total)
+	}
+	if r.NotifyURL == "" {
+		r.NotifyURL = p.PayRefundNotifyURL
+	}
+	if r.Amount.Currency == "" {
+		r.Amount.Currency = "CNY"
+	}
+
+	result := new(RefundResult)
+	if err := p.do(http.MethodPost, pathRefunds, r, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryRefund 按商户退款单号查询退款
+func (p *Pay) QueryRefund(outRefundNo string) (*RefundResult, error) {
+	result := new(RefundResult)
+	if err := p.do(http.MethodGet, pathRefunds+"/"+url.PathEscape(outRefundNo), nil, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
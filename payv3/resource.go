@@ -0,0 +1,40 @@
+package payv3
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/gotid/wechat/util"
+)
+
+const algorithmAESGCM = "AEAD_AES_256_GCM"
+
+// Resource 以 APIv3 密钥加密的数据，见于平台证书和回调通知
+type Resource struct {
+	Algorithm      string `json:"algorithm"`               // 加密算法，目前仅支持 AEAD_AES_256_GCM
+	Ciphertext     string `json:"ciphertext"`              // base64 编码的密文
+	AssociatedData string `json:"associated_data"`         // 附加数据
+	OriginalType   string `json:"original_type,omitempty"` // 原始类型
+	Nonce          string `json:"nonce"`                   // 随机串
+}
+
+// DecryptResource 使用 APIv3 密钥解密数据
+func (p *Pay) DecryptResource(r *Resource) ([]byte, error) {
+	if r.Algorithm != algorithmAESGCM {
+		return nil, fmt.Errorf("不支持的加密算法 %s", r.Algorithm)
+	}
+	if len(p.PayAPIv3Key) != 32 {
+		return nil, fmt.Errorf("商户 %s 的 APIv3 密钥须为 32 字节", p.PayMchID)
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(r.Ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("密文不是有效的 base64 编码：%w", err)
+	}
+
+	plaintext, err := util.AesGCMDecrypt(ciphertext, []byte(p.PayAPIv3Key), []byte(r.Nonce), []byte(r.AssociatedData))
+	if err != nil {
+		return nil, fmt.Errorf("解密数据失败：%w", err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,223 @@
+package payv3
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gotid/god/lib/grand"
+	"github.com/gotid/wechat/pay"
+	"github.com/gotid/wechat/util"
+)
+
+const (
+	pathTransactions          = "/v3/pay/transactions/"
+	pathTransactionByID       = "/v3/pay/transactions/id/"
+	pathTransactionByOutTrade = "/v3/pay/transactions/out-trade-no/"
+)
+
+type (
+	// Transaction 下单参数
+	// https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter3_5_1.shtml
+	Transaction struct {
+		AppID       string      `json:"appid"`                 // 应用ID，默认为上下文中的 AppID
+		MchID       string      `json:"mchid"`                 // 商户号，默认为上下文中的 PayMchID
+		Description string      `json:"description"`           // 商品描述，必填
+		OutTradeNo  string      `json:"out_trade_no"`          // 商户订单号，必填
+		TimeExpire  string      `json:"time_expire,omitempty"` // 交易结束时间，RFC3339 格式
+		Attach      string      `json:"attach,omitempty"`      // 附加数据，在查询和支付通知中原样返回
+		NotifyURL   string      `json:"notify_url"`            // 通知地址，默认为上下文中的 PayNotifyURL
+		GoodsTag    string      `json:"goods_tag,omitempty"`   // 订单优惠标记
+		Amount      Amount      `json:"amount"`                // 订单金额，必填
+		Payer       *Payer      `json:"payer,omitempty"`       // 支付者，JSAPI 必填
+		SceneInfo   *SceneInfo  `json:"scene_info,omitempty"`  // 场景信息，H5 必填
+		SettleInfo  *SettleInfo `json:"settle_info,omitempty"` // 结算信息
+	}
+
+	// Amount 订单金额
+	Amount struct {
+		Total         int64  `json:"total"`                    // 订单总金额，单位分
+		Currency      string `json:"currency,omitempty"`       // 货币类型，默认 CNY
+		PayerTotal    int64  `json:"payer_total,omitempty"`    // 用户支付金额，单位分，仅查询返回
+		PayerCurrency string `json:"payer_currency,omitempty"` // 用户支付币种，仅查询返回
+	}
+
+	// Payer 支付者
+	Payer struct {
+		OpenID string `json:"openid"` // 用户在应用下的唯一标识
+	}
+
+	// SceneInfo 场景信息
+	SceneInfo struct {
+		PayerClientIP string  `json:"payer_client_ip"`     // 用户终端IP
+		DeviceID      string  `json:"device_id,omitempty"` // 商户端设备号
+		H5Info        *H5Info `json:"h5_info,omitempty"`   // H5 场景信息
+	}
+
+	// H5Info H5 场景信息
+	H5Info struct {
+		Type string `json:"type"` // 场景类型 iOS/Android/Wap
+	}
+
+	// SettleInfo 结算信息
+	SettleInfo struct {
+		ProfitSharing bool `json:"profit_sharing"` // 是否指定分账
+	}
+
+	// TransactionResult 订单查询结果
+	TransactionResult struct {
+		AppID          string         `json:"appid"`            // 应用ID
+		MchID          string         `json:"mchid"`            // 商户号
+		OutTradeNo     string         `json:"out_trade_no"`     // 商户订单号
+		TransactionID  string         `json:"transaction_id"`   // 微信支付订单号
+		TradeType      pay.TradeType  `json:"trade_type"`       // 交易类型
+		TradeState     pay.TradeState `json:"trade_state"`      // 交易状态
+		TradeStateDesc string         `json:"trade_state_desc"` // 交易状态描述
+		BankType       string         `json:"bank_type"`        // 付款银行
+		Attach         string         `json:"attach"`           // 附加数据
+		SuccessTime    string         `json:"success_time"`     // 支付完成时间，RFC3339 格式
+		Payer          Payer          `json:"payer"`            // 支付者
+		Amount         Amount         `json:"amount"`           // 订单金额
+	}
+)
+
+// JSAPI 公众号、小程序下单，返回预支付交易会话标识
+func (p *Pay) JSAPI(t *Transaction) (prepayID string, err error) {
+	var result struct {
+		PrepayID string `json:"prepay_id"`
+	}
+	err = p.transact("jsapi", t, &result)
+	return result.PrepayID, err
+}
+
+// App APP 下单，返回预支付交易会话标识
+func (p *Pay) App(t *Transaction) (prepayID string, err error) {
+	var result struct {
+		PrepayID string `json:"prepay_id"`
+	}
+	err = p.transact("app", t, &result)
+	return result.PrepayID, err
+}
+
+// Native 扫码下单，返回二维码链接
+func (p *Pay) Native(t *Transaction) (codeURL string, err error) {
+	var result struct {
+		CodeURL string `json:"code_url"`
+	}
+	err = p.transact("native", t, &result)
+	return result.CodeURL, err
+}
+
+// H5 H5 下单，返回支付跳转链接
+func (p *Pay) H5(t *Transaction) (h5URL string, err error) {
+	var result struct {
+		H5URL string `json:"h5_url"`
+	}
+	err = p.transact("h5", t, &result)
+	return result.H5URL, err
+}
+
+func (p *Pay) transact(tradeType string, t *Transaction, result interface{}) error {
+	if t.AppID == "" {
+		t.AppID = p.AppID
+	}
+	if t.MchID == "" {
+		t.MchID = p.PayMchID
+	}
+	if t.NotifyURL == "" {
+		t.NotifyURL = p.PayNotifyURL
+	}
+
+	return p.do(http.MethodPost, pathTransactions+tradeType, t, result)
+}
+
+// QueryByTransactionID 按微信支付订单号查询订单
+func (p *Pay) QueryByTransactionID(transactionID string) (*TransactionResult, error) {
+	result := new(TransactionResult)
+	path := pathTransactionByID + url.PathEscape(transactionID) + "?mchid=" + url.QueryEscape(p.PayMchID)
+	if err := p.do(http.MethodGet, path, nil, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryByOutTradeNo 按商户订单号查询订单
+func (p *Pay) QueryByOutTradeNo(outTradeNo string) (*TransactionResult, error) {
+	result := new(TransactionResult)
+	path := pathTransactionByOutTrade + url.PathEscape(outTradeNo) + "?mchid=" + url.QueryEscape(p.PayMchID)
+	if err := p.do(http.MethodGet, path, nil, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Close 关闭未支付的订单
+func (p *Pay) Close(outTradeNo string) error {
+	body := map[string]string{"mchid": p.PayMchID}
+	return p.do(http.MethodPost, pathTransactionByOutTrade+url.PathEscape(outTradeNo)+"/close", body, nil)
+}
+
+// JSAPIParams 返回公众号内调起支付的参数
+func (p *Pay) JSAPIParams(prepayID string) (*pay.JSAPIParams, error) {
+	params := &pay.JSAPIParams{
+		AppID:     p.AppID,
+		TimeStamp: strconv.FormatInt(time.Now().Unix(), 10),
+		NonceStr:  grand.S(32),
+		Package:   "prepay_id=" + prepayID,
+		SignType:  "RSA",
+	}
+
+	sign, err := p.signMessage(params.AppID, params.TimeStamp, params.NonceStr, params.Package)
+	if err != nil {
+		return nil, err
+	}
+	params.PaySign = sign
+
+	return params, nil
+}
+
+// MiniProgramParams 返回小程序调起支付的参数
+func (p *Pay) MiniProgramParams(prepayID string) (*pay.MiniProgramParams, error) {
+	params, err := p.JSAPIParams(prepayID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pay.MiniProgramParams{
+		TimeStamp: params.TimeStamp,
+		NonceStr:  params.NonceStr,
+		Package:   params.Package,
+		SignType:  params.SignType,
+		PaySign:   params.PaySign,
+	}, nil
+}
+
+// AppParams 返回 APP 调起支付的参数
+func (p *Pay) AppParams(prepayID string) (*pay.AppParams, error) {
+	params := &pay.AppParams{
+		AppID:     p.AppID,
+		PartnerID: p.PayMchID,
+		PrepayID:  prepayID,
+		Package:   "Sign=WXPay",
+		NonceStr:  grand.S(32),
+		TimeStamp: strconv.FormatInt(time.Now().Unix(), 10),
+	}
+
+	sign, err := p.signMessage(params.AppID, params.TimeStamp, params.NonceStr, params.PrepayID)
+	if err != nil {
+		return nil, err
+	}
+	params.Sign = sign
+
+	return params, nil
+}
+
+// 使用商户私钥对按行拼接的字段签名
+func (p *Pay) signMessage(fields ...string) (string, error) {
+	key, err := p.privateKey()
+	if err != nil {
+		return "", err
+	}
+	return util.RSASignSHA256(key, buildMessage(fields...))
+}
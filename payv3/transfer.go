@@ -0,0 +1,243 @@
+package payv3
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gotid/wechat/util"
+)
+
+const (
+	pathTransferBatches           = "/v3/transfer/batches"
+	pathTransferBatchByOutBatchNo = "/v3/transfer/batches/out-batch-no/"
+	pathTransferBatchByBatchID    = "/v3/transfer/batches/batch-id/"
+)
+
+// BatchStatus 转账批次状态
+type BatchStatus string
+
+const (
+	BatchStatusWaitPay    BatchStatus = "WAIT_PAY"   // 待付款确认
+	BatchStatusAccepted   BatchStatus = "ACCEPTED"   // 已受理
+	BatchStatusProcessing BatchStatus = "PROCESSING" // 转账中
+	BatchStatusFinished   BatchStatus = "FINISHED"   // 已完成，明细可能部分失败
+	BatchStatusClosed     BatchStatus = "CLOSED"     // 已关闭
+)
+
+// Terminal 是否为终态
+func (s BatchStatus) Terminal() bool {
+	return s == BatchStatusFinished || s == BatchStatusClosed
+}
+
+// DetailStatus 转账明细状态
+type DetailStatus string
+
+const (
+	DetailStatusInit       DetailStatus = "INIT"       // 初始态
+	DetailStatusWaitPay    DetailStatus = "WAIT_PAY"   // 待确认
+	DetailStatusProcessing DetailStatus = "PROCESSING" // 转账中
+	DetailStatusSuccess    DetailStatus = "SUCCESS"    // 转账成功
+	DetailStatusFail       DetailStatus = "FAIL"       // 转账失败
+)
+
+// 批量转账常见的错误，可通过 errors.Is 判断
+var (
+	ErrNotEnough      = &Error{Code: "NOT_ENOUGH"}      // 商户资金不足
+	ErrNoAuth         = &Error{Code: "NO_AUTH"}         // 商户无权限
+	ErrInvalidRequest = &Error{Code: "INVALID_REQUEST"} // 批次号重复使用且参数不一致等
+	ErrSystemError    = &Error{Code: "SYSTEM_ERROR"}    // 系统错误，须以原批次号重试
+)
+
+// 轮询批次状态的默认间隔
+const defaultBatchPollInterval = 10 * time.Second
+
+type (
+	// TransferBatch 发起商家转账批次参数，同一商户批次号重复请求时微信不会重复转账
+	// https://pay.weixin.qq.com/wiki/doc/apiv3/apis/chapter4_3_1.shtml
+	TransferBatch struct {
+		AppID           string            `json:"appid"`                       // 应用ID，默认为上下文中的 AppID
+		OutBatchNo      string            `json:"out_batch_no"`                // 商户批次单号，必填
+		BatchName       string            `json:"batch_name"`                  // 批次名称，必填
+		BatchRemark     string            `json:"batch_remark"`                // 批次备注，必填
+		TotalAmount     int64             `json:"total_amount"`                // 转账总金额，单位分，默认为明细金额之和
+		TotalNum        int               `json:"total_num"`                   // 转账总笔数，默认为明细笔数
+		Details         []*TransferDetail `json:"transfer_detail_list"`        // 转账明细列表，必填
+		TransferSceneID string            `json:"transfer_scene_id,omitempty"` // 转账场景ID
+	}
+
+	// TransferDetail 转账明细
+	TransferDetail struct {
+		OutDetailNo    string `json:"out_detail_no"`       // 商户明细单号，必填
+		TransferAmount int64  `json:"transfer_amount"`     // 转账金额，单位分，必填
+		TransferRemark string `json:"transfer_remark"`     // 转账备注，必填
+		OpenID         string `json:"openid"`              // 收款用户 openid，必填
+		UserName       string `json:"user_name,omitempty"` // 收款用户姓名明文，发起时以平台证书加密
+	}
+
+	// TransferBatchResult 发起转账批次结果
+	TransferBatchResult struct {
+		OutBatchNo string `json:"out_batch_no"` // 商户批次单号
+		BatchID    string `json:"batch_id"`     // 微信批次单号
+		CreateTime string `json:"create_time"`  // 批次创建时间，RFC3339 格式
+	}
+
+	// TransferBatchInfo 转账批次查询结果
+	TransferBatchInfo struct {
+		Batch struct {
+			MchID         string      `json:"mchid"`          // 商户号
+			OutBatchNo    string      `json:"out_batch_no"`   // 商户批次单号
+			BatchID       string      `json:"batch_id"`       // 微信批次单号
+			AppID         string      `json:"appid"`          // 应用ID
+			BatchStatus   BatchStatus `json:"batch_status"`   // 批次状态
+			BatchName     string      `json:"batch_name"`     // 批次名称
+			BatchRemark   string      `json:"batch_remark"`   // 批次备注
+			CloseReason   string      `json:"close_reason"`   // 批次关闭原因
+			TotalAmount   int64       `json:"total_amount"`   // 转账总金额，单位分
+			TotalNum      int         `json:"total_num"`      // 转账总笔数
+			CreateTime    string      `json:"create_time"`    // 批次创建时间
+			UpdateTime    string      `json:"update_time"`    // 批次更新时间
+			SuccessAmount int64       `json:"success_amount"` // 转账成功金额，单位分
+			SuccessNum    int         `json:"success_num"`    // 转账成功笔数
+			FailAmount    int64       `json:"fail_amount"`    // 转账失败金额，单位分
+			FailNum       int         `json:"fail_num"`       // 转账失败笔数
+		} `json:"transfer_batch"` // 转账批次单
+		Details []struct {
+			DetailID     string       `json:"detail_id"`     // 微信明细单号
+			OutDetailNo  string       `json:"out_detail_no"` // 商户明细单号
+			DetailStatus DetailStatus `json:"detail_status"` // 明细状态
+		} `json:"transfer_detail_list"` // 转账明细列表
+	}
+)
+
+// TransferBatch 发起商家转账批次，转账结果需查询批次状态。
+// 结果不确定（如 SYSTEM_ERROR 或网络超时）时须以原商户批次单号重试，切勿更换单号。
+func (p *Pay) TransferBatch(b *TransferBatch) (*TransferBatchResult, error) {
+	if b.OutBatchNo == "" {
+		return nil, errors.New("转账批次缺少商户批次单号")
+	}
+	if len(b.Details) == 0 {
+		return nil, fmt.Errorf("转账批次 %s 缺少转账明细", b.OutBatchNo)
+	}
+	if b.AppID == "" {
+		b.AppID = p.AppID
+	}
+
+	var total int64
+	for _, d := range b.Details {
+		if d.TransferAmount <= 0 {
+			return nil, fmt.Errorf("转账明细 %s 的金额 %d 须大于 0", d.OutDetailNo, d.TransferAmount)
+		}
+		total += d.TransferAmount
+	}
+	if b.TotalAmount == 0 {
+		b.TotalAmount = total
+	}
+	if b.TotalNum == 0 {
+		b.TotalNum = len(b.Details)
+	}
+	if b.TotalAmount != total || b.TotalNum != len(b.Details) {
+		return nil, fmt.Errorf("转账批次 %s 的总金额或总笔数与明细不一致", b.OutBatchNo)
+	}
+
+	// 姓名以平台证书加密，并告知所用证书序列号
+	body := *b
+	body.Details = make([]*TransferDetail, len(b.Details))
+	var header http.Header
+	for i, d := range b.Details {
+		detail := *d
+		if d.UserName != "" {
+			serial, ciphertext, err := p.encryptSensitive(d.UserName)
+			if err != nil {
+				return nil, err
+			}
+			detail.UserName = ciphertext
+			header = http.Header{headerSerial: {serial}}
+		}
+		body.Details[i] = &detail
+	}
+
+	result := new(TransferBatchResult)
+	if err := p.doWithHeader(http.MethodPost, pathTransferBatches, header, &body, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// QueryTransferBatch 按商户批次单号查询转账批次，needDetail 为真时返回转账明细
+func (p *Pay) QueryTransferBatch(outBatchNo string, needDetail bool) (*TransferBatchInfo, error) {
+	return p.queryTransferBatch(pathTransferBatchByOutBatchNo+url.PathEscape(outBatchNo), needDetail)
+}
+
+// QueryTransferBatchByID 按微信批次单号查询转账批次，needDetail 为真时返回转账明细
+func (p *Pay) QueryTransferBatchByID(batchID string, needDetail bool) (*TransferBatchInfo, error) {
+	return p.queryTransferBatch(pathTransferBatchByBatchID+url.PathEscape(batchID), needDetail)
+}
+
+func (p *Pay) queryTransferBatch(path string, needDetail bool) (*TransferBatchInfo, error) {
+	query := url.Values{"need_query_detail": {fmt.Sprint(needDetail)}}
+	if needDetail {
+		query.Set("detail_status", "ALL")
+	}
+
+	result := new(TransferBatchInfo)
+	if err := p.do(http.MethodGet, path+"?"+query.Encode(), nil, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WaitTransferBatch 按间隔轮询转账批次，直至批次完成或关闭，或 ctx 结束。
+// interval 不大于 0 时使用默认间隔，查询出错时继续轮询。
+func (p *Pay) WaitTransferBatch(ctx context.Context, outBatchNo string, interval time.Duration) (*TransferBatchInfo, error) {
+	if interval <= 0 {
+		interval = defaultBatchPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		info, err := p.QueryTransferBatch(outBatchNo, true)
+		if err == nil && info.Batch.BatchStatus.Terminal() {
+			return info, nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return nil, fmt.Errorf("等待转账批次 %s 完成超时：%w", outBatchNo, lastErr)
+			}
+			return nil, fmt.Errorf("等待转账批次 %s 完成超时：%w", outBatchNo, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// 以最新的平台证书加密敏感信息，返回证书序列号和密文
+func (p *Pay) encryptSensitive(plaintext string) (string, string, error) {
+	provider, ok := p.certs.(latestCertificateProvider)
+	if !ok {
+		return "", "", fmt.Errorf("商户 %s 的平台证书不支持加密敏感信息", p.PayMchID)
+	}
+	serial, cert, err := provider.LatestCertificate()
+	if err != nil {
+		return "", "", err
+	}
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return "", "", fmt.Errorf("平台证书 %s 不是 RSA 证书", serial)
+	}
+
+	ciphertext, err := util.RSAEncryptOAEP(key, []byte(plaintext))
+	if err != nil {
+		return "", "", err
+	}
+	return serial, ciphertext, nil
+}
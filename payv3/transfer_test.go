@@ -0,0 +1,81 @@
+package payv3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPay_TransferBatch(t *testing.T) {
+	defer mockServer(t, func(r *http.Request, body []byte) (int, interface{}) {
+		assert.Equal(t, pathTransferBatches, r.URL.Path)
+		assert.Equal(t, testPlatformSerial, r.Header.Get(headerSerial))
+
+		var req TransferBatch
+		assert.Nil(t, json.Unmarshal(body, &req))
+		assert.Equal(t, "wxd678efh567hg6787", req.AppID)
+		assert.Equal(t, int64(300), req.TotalAmount)
+		assert.Equal(t, 2, req.TotalNum)
+		name, err := util.RSADecryptOAEP(testPlatformKey, req.Details[1].UserName)
+		assert.Nil(t, err)
+		assert.Equal(t, "张三", string(name))
+
+		if req.OutBatchNo == "plfk2020042014" {
+			return http.StatusForbidden, map[string]string{"code": "NOT_ENOUGH", "message": "资金不足"}
+		}
+		return http.StatusOK, map[string]string{"out_batch_no": req.OutBatchNo, "batch_id": "1030000071100999991182020050700019480001"}
+	})()
+
+	p := newTestPay()
+	batch := &TransferBatch{
+		OutBatchNo:  "plfk2020042013",
+		BatchName:   "分销佣金",
+		BatchRemark: "2020年4月分销佣金",
+		Details: []*TransferDetail{
+			{OutDetailNo: "x23zy545Bd5436", TransferAmount: 100, TransferRemark: "佣金", OpenID: "o-MYE42l80oelYMDE34nYD456Xoy"},
+			{OutDetailNo: "x23zy545Bd5437", TransferAmount: 200, TransferRemark: "佣金", OpenID: "o-MYE42l80oelYMDE34nYD456Xoz", UserName: "张三"},
+		},
+	}
+	result, err := p.TransferBatch(batch)
+	assert.Nil(t, err)
+	assert.Equal(t, "1030000071100999991182020050700019480001", result.BatchID)
+	assert.Equal(t, "张三", batch.Details[1].UserName, "不得修改调用方的明文姓名")
+
+	batch.OutBatchNo = "plfk2020042014"
+	_, err = p.TransferBatch(batch)
+	assert.True(t, errors.Is(err, ErrNotEnough))
+}
+
+func TestPay_WaitTransferBatch(t *testing.T) {
+	var queries int32
+	defer mockServer(t, func(r *http.Request, body []byte) (int, interface{}) {
+		assert.Equal(t, pathTransferBatchByOutBatchNo+"plfk2020042013", r.URL.Path)
+
+		status := BatchStatusProcessing
+		if atomic.AddInt32(&queries, 1) >= 3 {
+			status = BatchStatusFinished
+		}
+		return http.StatusOK, map[string]interface{}{
+			"transfer_batch": map[string]interface{}{"out_batch_no": "plfk2020042013", "batch_status": status, "success_num": 2},
+		}
+	})()
+
+	p := newTestPay()
+	info, err := p.WaitTransferBatch(context.Background(), "plfk2020042013", time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, BatchStatusFinished, info.Batch.BatchStatus)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&queries))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	atomic.StoreInt32(&queries, -1000)
+	_, err = p.WaitTransferBatch(ctx, "plfk2020042013", time.Millisecond)
+	assert.NotNil(t, err, "超时前未完成时返回错误")
+}
@@ -0,0 +1,12 @@
+package server
+
+import "errors"
+
+var (
+	// ErrInvalidSignature 微信请求签名校验失败
+	ErrInvalidSignature = errors.New("微信请求签名校验失败")
+	// ErrInvalidRequest 微信请求无法读取或解析
+	ErrInvalidRequest = errors.New("微信请求无效")
+	// ErrNoHandler 未设置处理该通知的钩子，不得向微信确认已处理
+	ErrNoHandler = errors.New("未设置通知钩子")
+)
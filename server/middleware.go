@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/gotid/god/lib/logx"
+	"github.com/gotid/wechat/msg"
+)
+
+type (
+	// Handler 处理验签解密后的微信消息并返回响应
+	Handler func(s *Server) (*msg.Response, error)
+
+	// Middleware 消息中间件，包裹下一个处理器以注入通用逻辑
+	Middleware func(next Handler) Handler
+)
+
+// Use 注册消息中间件，按注册顺序由外向内执行
+func (s *Server) Use(mws ...Middleware) {
+	s.middlewares = append(s.middlewares, mws...)
+}
+
+// RequestRaw 返回验签解密后的原始请求数据
+func (s *Server) RequestRaw() []byte {
+	return s.requestRaw
+}
+
+// RequestMsg 返回解析后的请求消息
+func (s *Server) RequestMsg() msg.Msg {
+	return s.requestMsg
+}
+
+// 组装中间件链，最内层调用常规消息钩子
+func (s *Server) chain() Handler {
+	var h Handler = func(s *Server) (*msg.Response, error) {
+		if s.msgHandler == nil {
+			return nil, nil
+		}
+		return s.msgHandler(s.Context, s.requestMsg), nil
+	}
+
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		h = s.middlewares[i](h)
+	}
+
+	return h
+}
+
+// Recover 返回捕获消息处理异常的中间件
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(s *Server) (resp *msg.Response, err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					logx.Errorf("微信消息处理异常：%v\n%s", p, debug.Stack())
+					err = fmt.Errorf("微信消息处理异常：%v", p)
+				}
+			}()
+
+			return next(s)
+		}
+	}
+}
+
+// Log 返回记录消息处理日志的中间件
+func Log() Middleware {
+	return func(next Handler) Handler {
+		return func(s *Server) (*msg.Response, error) {
+			start := time.Now()
+			resp, err := next(s)
+			duration := time.Since(start)
+
+			if err != nil {
+				logx.WithDuration(duration).Errorf("微信消息处理失败：appid=%s, data=%s, err=%v",
+					s.AppID, s.requestRaw, err)
+			} else {
+				logx.WithDuration(duration).Infof("微信消息处理完成：appid=%s, data=%s, resp=%+v",
+					s.AppID, s.requestRaw, resp)
+			}
+
+			return resp, err
+		}
+	}
+}
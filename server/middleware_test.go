@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer_Use(t *testing.T) {
+	var trace []string
+	mark := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(s *Server) (*msg.Response, error) {
+				trace = append(trace, name+">")
+				resp, err := next(s)
+				trace = append(trace, "<"+name)
+				return resp, err
+			}
+		}
+	}
+
+	s := NewServer(&context.Context{}, httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	s.requestMsg = msg.Msg{InfoType: msg.InfoTypeAuthorized}
+	s.SetMsgHandler(func(_ *context.Context, m msg.Msg) *msg.Response {
+		trace = append(trace, string(m.InfoType))
+		return &msg.Response{Scene: msg.ResponseSceneOpen}
+	})
+	s.Use(mark("a"), mark("b"))
+
+	resp, err := s.chain()(s)
+	assert.Nil(t, err)
+	assert.Equal(t, msg.ResponseSceneOpen, resp.Scene)
+	assert.Equal(t, []string{"a>", "b>", "authorized", "<b", "<a"}, trace)
+}
+
+func TestRecover(t *testing.T) {
+	s := NewServer(&context.Context{}, httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	s.SetMsgHandler(func(*context.Context, msg.Msg) *msg.Response {
+		panic("boom")
+	})
+	s.Use(Recover())
+
+	resp, err := s.chain()(s)
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, "微信消息处理异常：boom")
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+// 将参数签名后编码为微信支付 XML
+func signedPayXML(t *testing.T, params map[string]string, key string) []byte {
+	sign, err := util.ParamSign(params, key)
+	assert.Nil(t, err)
+	params["sign"] = sign
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("<xml>")
+	for _, k := range keys {
+		buf.WriteString("<" + k + "><![CDATA[" + params[k] + "]]></" + k + ">")
+	}
+	buf.WriteString("</xml>")
+	return buf.Bytes()
+}
+
+func payNotifyParams() map[string]string {
+	return map[string]string{
+		"return_code":    "SUCCESS",
+		"appid":          "wx2421b1c4370ec43b",
+		"mch_id":         "10000100",
+		"nonce_str":      "5d2b6c2a8db53831f7eda20af46e531c",
+		"result_code":    "SUCCESS",
+		"openid":         "oUpF8uMEb4qRXf22hE3X68TekukE",
+		"trade_type":     "JSAPI",
+		"total_fee":      "100",
+		"cash_fee":       "80",
+		"coupon_fee":     "20",
+		"coupon_count":   "2",
+		"coupon_id_0":    "10000",
+		"coupon_fee_0":   "15",
+		"coupon_type_0":  "CASH",
+		"coupon_id_1":    "10001",
+		"coupon_fee_1":   "5",
+		"transaction_id": "1004400740201409030005092168",
+		"out_trade_no":   "1409811653",
+		"attach":         "支付测试",
+		"time_end":       "20140903131540",
+	}
+}
+
+func servePay(t *testing.T, ctx *context.Context, body []byte, h PayHandlerFunc) (*httptest.ResponseRecorder, error) {
+	w := httptest.NewRecorder()
+	s := NewServer(ctx, w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+	s.SetPayHandler(h)
+	if err := s.Serve(); err != nil {
+		return w, err
+	}
+	s.Send()
+	return w, nil
+}
+
+func TestServer_HandlePay(t *testing.T) {
+	ctx := &context.Context{PayMchID: "10000100", PayKey: "192006250b4c09247ec02edce69f6a2d"}
+
+	for _, signType := range []string{"", util.SignTypeMD5, util.SignTypeHMACSHA256} {
+		params := payNotifyParams()
+		if signType != "" {
+			params["sign_type"] = signType
+		}
+
+		var notify *msg.PayNotify
+		w, err := servePay(t, ctx, signedPayXML(t, params, ctx.PayKey),
+			func(_ *context.Context, n *msg.PayNotify) *msg.Response {
+				notify = n
+				return nil
+			})
+		assert.Nil(t, err)
+		assert.NotNil(t, notify)
+		assert.True(t, notify.Success())
+		assert.Equal(t, "1409811653", notify.OutTradeNo)
+		assert.Equal(t, int64(100), notify.TotalFee)
+		assert.Equal(t, "支付测试", notify.Attach)
+		assert.Equal(t, []msg.Coupon{
+			{ID: "10000", Type: "CASH", Fee: 15},
+			{ID: "10001", Fee: 5},
+		}, notify.Coupons)
+
+		var resp msg.PayNotifyResponse
+		assert.Nil(t, xml.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "SUCCESS", resp.ReturnCode)
+	}
+}
+
+func TestServer_HandlePayInvalidSign(t *testing.T) {
+	ctx := &context.Context{PayMchID: "10000100", PayKey: "192006250b4c09247ec02edce69f6a2d"}
+	body := signedPayXML(t, payNotifyParams(), "wrong key")
+	body = bytes.Replace(body, []byte("<total_fee><![CDATA[100]]>"), []byte("<total_fee><![CDATA[1]]>"), 1)
+
+	called := false
+	_, err := servePay(t, ctx, body, func(*context.Context, *msg.PayNotify) *msg.Response {
+		called = true
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+	assert.False(t, called)
+
+	// 缺少签名
+	params := payNotifyParams()
+	_, err = servePay(t, ctx, []byte(strings.Replace(string(signedPayXML(t, params, ctx.PayKey)),
+		"<sign><![CDATA["+params["sign"]+"]]></sign>", "", 1)), nil)
+	assert.True(t, errors.Is(err, ErrInvalidSignature))
+}
+
+func TestServer_HandlePayWithoutHandler(t *testing.T) {
+	ctx := &context.Context{PayMchID: "10000100", PayKey: "192006250b4c09247ec02edce69f6a2d"}
+
+	// 未设置支付钩子时不得回复成功
+	w, err := servePay(t, ctx, signedPayXML(t, payNotifyParams(), ctx.PayKey), nil)
+	assert.True(t, errors.Is(err, ErrNoHandler))
+	assert.NotContains(t, w.Body.String(), "SUCCESS")
+}
+
+// 按退款通知规则加密 req_info
+func encryptReqInfo(t *testing.T, plaintext, payKey string) string {
+	sum := md5.Sum([]byte(payKey))
+	block, err := aes.NewCipher([]byte(hex.EncodeToString(sum[:])))
+	assert.Nil(t, err)
+
+	data := util.PKCS5Padding([]byte(plaintext), aes.BlockSize)
+	util.NewECBEncryptor(block).CryptBlocks(data, data)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func TestServer_HandleRefund(t *testing.T) {
+	ctx := &context.Context{PayMchID: "10000100", PayKey: "192006250b4c09247ec02edce69f6a2d"}
+	info := "<root><out_refund_no><![CDATA[131811191610442717309]]></out_refund_no>" +
+		"<out_trade_no><![CDATA[71106718111915575302817]]></out_trade_no>" +
+		"<refund_account><![CDATA[REFUND_SOURCE_RECHARGE_FUNDS]]></refund_account>" +
+		"<refund_fee><![CDATA[3960]]></refund_fee>" +
+		"<refund_id><![CDATA[50000408942018111907145868882]]></refund_id>" +
+		"<refund_recv_accout><![CDATA[支付用户零钱]]></refund_recv_accout>" +
+		"<refund_request_source><![CDATA[API]]></refund_request_source>" +
+		"<refund_status><![CDATA[SUCCESS]]></refund_status>" +
+		"<settlement_refund_fee><![CDATA[3960]]></settlement_refund_fee>" +
+		"<settlement_total_fee><![CDATA[3960]]></settlement_total_fee>" +
+		"<success_time><![CDATA[2018-11-19 16:24:13]]></success_time>" +
+		"<total_fee><![CDATA[3960]]></total_fee>" +
+		"<transaction_id><![CDATA[4200000215201811190261405420]]></transaction_id></root>"
+	body := func(reqInfo string) []byte {
+		return []byte("<xml><return_code>SUCCESS</return_code><appid><![CDATA[wx2421b1c4370ec43b]]></appid>" +
+			"<mch_id><![CDATA[10000100]]></mch_id><nonce_str><![CDATA[TeqClE3i0mvn3DrK]]></nonce_str>" +
+			"<req_info><![CDATA[" + reqInfo + "]]></req_info></xml>")
+	}
+
+	var notify *msg.RefundNotify
+	w := httptest.NewRecorder()
+	s := NewServer(ctx, w, httptest.NewRequest(http.MethodPost, "/",
+		bytes.NewReader(body(encryptReqInfo(t, info, ctx.PayKey)))))
+	s.SetRefundHandler(func(_ *context.Context, n *msg.RefundNotify) *msg.Response {
+		notify = n
+		return nil
+	})
+	assert.Nil(t, s.Serve())
+	s.Send()
+
+	assert.NotNil(t, notify)
+	assert.True(t, notify.Success())
+	assert.Equal(t, "50000408942018111907145868882", notify.RefundID)
+	assert.Equal(t, "131811191610442717309", notify.OutRefundNo)
+	assert.Equal(t, int64(3960), notify.SettlementRefundFee)
+	assert.Equal(t, "支付用户零钱", notify.RefundRecvAccout)
+	assert.Equal(t, "2018-11-19 16:24:13", notify.SuccessTime)
+	assert.Contains(t, w.Body.String(), "<return_code>SUCCESS</return_code>")
+
+	// 使用错误密钥加密的通知不得进入钩子
+	notify = nil
+	s = NewServer(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/",
+		bytes.NewReader(body(encryptReqInfo(t, info, "wrong key")))))
+	s.SetRefundHandler(func(_ *context.Context, n *msg.RefundNotify) *msg.Response {
+		notify = n
+		return nil
+	})
+	assert.True(t, errors.Is(s.Serve(), ErrInvalidSignature))
+	assert.Nil(t, notify)
+
+	// 未设置退款钩子时不得回复成功
+	w = httptest.NewRecorder()
+	s = NewServer(ctx, w, httptest.NewRequest(http.MethodPost, "/",
+		bytes.NewReader(body(encryptReqInfo(t, info, ctx.PayKey)))))
+	assert.True(t, errors.Is(s.Serve(), ErrNoHandler))
+	assert.NotContains(t, w.Body.String(), "SUCCESS")
+}
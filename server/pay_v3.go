@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/payv3"
+)
+
+// 微信支付 APIv3 回调的签名头
+const headerPayV3Signature = "Wechatpay-Signature"
+
+// 处理并回复微信支付 APIv3 回调通知
+func (s *Server) handlePayV3() (reply *msg.Response, err error) {
+	p := payv3.NewPay(s.Context, s.payV3Certs)
+
+	// 校验签名，签名错误的通知不得进入钩子
+	if err = p.VerifySignature(s.request.Header, s.requestRaw); err != nil {
+		err = fmt.Errorf("%w：微信支付回调签名不匹配：%v", ErrInvalidSignature, err)
+		return
+	}
+
+	var notify struct {
+		msg.NotifyV3
+		Resource payv3.Resource `json:"resource"`
+	}
+	if err = json.Unmarshal(s.requestRaw, &notify); err != nil {
+		err = fmt.Errorf("%w：解析微信支付回调失败：data=%s, err=%v", ErrInvalidRequest, s.requestRaw, err)
+		return
+	}
+
+	data, err := p.DecryptResource(&notify.Resource)
+	if err != nil {
+		err = fmt.Errorf("%w：微信支付回调解密失败：%v", ErrInvalidSignature, err)
+		return
+	}
+
+	switch {
+	case strings.HasPrefix(notify.EventType, "TRANSACTION."):
+		reply, err = s.handlePayV3Transaction(notify.NotifyV3, data)
+	case strings.HasPrefix(notify.EventType, "REFUND."):
+		reply, err = s.handlePayV3Refund(notify.NotifyV3, data)
+	default:
+		err = fmt.Errorf("%w：不支持的微信支付回调类型 %s", ErrInvalidRequest, notify.EventType)
+	}
+	if err != nil {
+		return
+	}
+
+	if reply == nil {
+		reply = &msg.Response{}
+	}
+	reply.Scene = msg.ResponseScenePay
+	if reply.Type == "" {
+		reply.Type = msg.ResponseTypeJSON
+	}
+	if reply.Msg == nil {
+		reply.Msg = msg.PayNotifyV3Response{Code: "SUCCESS", Message: "成功"}
+	}
+
+	return
+}
+
+// 调用 APIv3 支付钩子
+func (s *Server) handlePayV3Transaction(n msg.NotifyV3, data []byte) (*msg.Response, error) {
+	notify := &msg.PayNotifyV3{NotifyV3: n}
+	if err := json.Unmarshal(data, notify); err != nil {
+		return nil, fmt.Errorf("%w：解析支付结果失败：%v", ErrInvalidRequest, err)
+	}
+
+	// 未设置钩子时不得回复成功，以免微信停止重试
+	if s.payV3Handler == nil {
+		return nil, fmt.Errorf("%w：APIv3 支付通知 out_trade_no=%s", ErrNoHandler, notify.OutTradeNo)
+	}
+	return s.payV3Handler(s.Context, notify), nil
+}
+
+// 调用 APIv3 退款钩子
+func (s *Server) handlePayV3Refund(n msg.NotifyV3, data []byte) (*msg.Response, error) {
+	notify := &msg.RefundNotifyV3{NotifyV3: n}
+	if err := json.Unmarshal(data, notify); err != nil {
+		return nil, fmt.Errorf("%w：解析退款结果失败：%v", ErrInvalidRequest, err)
+	}
+
+	// 未设置钩子时不得回复成功，以免微信停止重试
+	if s.refundV3Handler == nil {
+		return nil, fmt.Errorf("%w：APIv3 退款通知 out_refund_no=%s", ErrNoHandler, notify.OutRefundNo)
+	}
+	return s.refundV3Handler(s.Context, notify), nil
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/payv3"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testAPIv3Key       = "a7cde1ef7a2b4e5c8d9f0a1b2c3d4e5f"
+	testPlatformSerial = "5157F09EFDC096DE15EBE81A47057A7232F1B8E1"
+)
+
+// 返回平台私钥及以其自签的平台证书
+func newPlatformCertificate(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Tenpay.com Root CA"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return key, cert
+}
+
+// 构造以 APIv3 密钥加密业务数据、以平台私钥签名的回调请求
+func newPayV3Request(t *testing.T, key *rsa.PrivateKey, eventType string, resource interface{}) *http.Request {
+	plaintext, err := json.Marshal(resource)
+	assert.Nil(t, err)
+	block, err := aes.NewCipher([]byte(testAPIv3Key))
+	assert.Nil(t, err)
+	gcm, err := cipher.NewGCM(block)
+	assert.Nil(t, err)
+
+	nonce, associatedData := "fdasfjihihihlkja484w", "transaction"
+	body, err := json.Marshal(map[string]interface{}{
+		"id":            "EV-2018022511223320873",
+		"create_time":   "2015-05-20T13:29:35+08:00",
+		"event_type":    eventType,
+		"resource_type": "encrypt-resource",
+		"summary":       "支付成功",
+		"resource": payv3.Resource{
+			Algorithm:      "AEAD_AES_256_GCM",
+			Ciphertext:     base64.StdEncoding.EncodeToString(gcm.Seal(nil, []byte(nonce[:12]), plaintext, []byte(associatedData))),
+			AssociatedData: associatedData,
+			Nonce:          nonce[:12],
+		},
+	})
+	assert.Nil(t, err)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := util.RSASignSHA256(key, []byte(timestamp+"\n"+"5K8264ILTKCH16CQ2502SI8ZNMTM67VS\n"+string(body)+"\n"))
+	assert.Nil(t, err)
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Wechatpay-Timestamp", timestamp)
+	r.Header.Set("Wechatpay-Nonce", "5K8264ILTKCH16CQ2502SI8ZNMTM67VS")
+	r.Header.Set("Wechatpay-Signature", signature)
+	r.Header.Set("Wechatpay-Serial", testPlatformSerial)
+	return r
+}
+
+func TestServer_HandlePayV3(t *testing.T) {
+	key, cert := newPlatformCertificate(t)
+	ctx := &context.Context{PayMchID: "1230000109", PayAPIv3Key: testAPIv3Key}
+	certs := payv3.Certificates{testPlatformSerial: cert}
+
+	var notify *msg.PayNotifyV3
+	w := httptest.NewRecorder()
+	s := NewServer(ctx, w, newPayV3Request(t, key, msg.NotifyEventTransactionSuccess, map[string]interface{}{
+		"mchid":          "1230000109",
+		"out_trade_no":   "1217752501201407033233368018",
+		"transaction_id": "1217752501201407033233368018",
+		"trade_state":    "SUCCESS",
+		"payer":          map[string]string{"openid": "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o"},
+		"amount":         map[string]interface{}{"total": 100, "payer_total": 100},
+	}))
+	s.SetPayV3Certificates(certs)
+	s.SetPayV3Handler(func(_ *context.Context, n *msg.PayNotifyV3) *msg.Response {
+		notify = n
+		return nil
+	})
+	assert.Nil(t, s.Serve())
+	s.Send()
+
+	assert.NotNil(t, notify)
+	assert.True(t, notify.Success())
+	assert.Equal(t, msg.NotifyEventTransactionSuccess, notify.EventType)
+	assert.Equal(t, "1217752501201407033233368018", notify.OutTradeNo)
+	assert.Equal(t, "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o", notify.Payer.OpenID)
+	assert.Equal(t, int64(100), notify.Amount.Total)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"code":"SUCCESS","message":"成功"}`, w.Body.String())
+
+	// 退款结果通知
+	var refund *msg.RefundNotifyV3
+	s = NewServer(ctx, httptest.NewRecorder(), newPayV3Request(t, key, msg.NotifyEventRefundSuccess, map[string]interface{}{
+		"out_refund_no": "1217752501201407033233368018-1",
+		"refund_status": "SUCCESS",
+		"amount":        map[string]interface{}{"total": 100, "refund": 30},
+	}))
+	s.SetPayV3Certificates(certs)
+	s.SetRefundV3Handler(func(_ *context.Context, n *msg.RefundNotifyV3) *msg.Response {
+		refund = n
+		return nil
+	})
+	assert.Nil(t, s.Serve())
+	assert.True(t, refund.Success())
+	assert.Equal(t, int64(30), refund.Amount.Refund)
+}
+
+func TestServer_HandlePayV3InvalidSign(t *testing.T) {
+	key, cert := newPlatformCertificate(t)
+	ctx := &context.Context{PayMchID: "1230000109", PayAPIv3Key: testAPIv3Key}
+
+	r := newPayV3Request(t, key, msg.NotifyEventTransactionSuccess, map[string]string{"trade_state": "SUCCESS"})
+	r.Header.Set("Wechatpay-Timestamp", strconv.FormatInt(time.Now().Unix()+1, 10))
+
+	called := false
+	s := NewServer(ctx, httptest.NewRecorder(), r)
+	s.SetPayV3Certificates(payv3.Certificates{testPlatformSerial: cert})
+	s.SetPayV3Handler(func(*context.Context, *msg.PayNotifyV3) *msg.Response {
+		called = true
+		return nil
+	})
+	assert.True(t, errors.Is(s.Serve(), ErrInvalidSignature))
+	assert.False(t, called)
+}
+
+func TestServer_HandlePayV3WithoutHandler(t *testing.T) {
+	key, cert := newPlatformCertificate(t)
+	ctx := &context.Context{PayMchID: "1230000109", PayAPIv3Key: testAPIv3Key}
+	certs := payv3.Certificates{testPlatformSerial: cert}
+
+	// 未设置钩子时不得回复成功
+	w := httptest.NewRecorder()
+	s := NewServer(ctx, w, newPayV3Request(t, key, msg.NotifyEventTransactionSuccess, map[string]string{
+		"out_trade_no": "1217752501201407033233368018",
+		"trade_state":  "SUCCESS",
+	}))
+	s.SetPayV3Certificates(certs)
+	assert.True(t, errors.Is(s.Serve(), ErrNoHandler))
+	assert.NotContains(t, w.Body.String(), "SUCCESS")
+
+	w = httptest.NewRecorder()
+	s = NewServer(ctx, w, newPayV3Request(t, key, msg.NotifyEventRefundSuccess, map[string]string{
+		"out_refund_no": "1217752501201407033233368018-1",
+		"refund_status": "SUCCESS",
+	}))
+	s.SetPayV3Certificates(certs)
+	assert.True(t, errors.Is(s.Serve(), ErrNoHandler))
+	assert.NotContains(t, w.Body.String(), "SUCCESS")
+}
@@ -0,0 +1,16 @@
+package server
+
+// Query 返回网址中查询键的值。
+func (s *Server) Query(key string) string {
+	v, _ := s.GetQuery(key)
+	return v
+}
+
+// GetQuery 返回网址中查询键的值及存在状态。
+func (s *Server) GetQuery(key string) (string, bool) {
+	if vs, ok := s.query[key]; ok && len(vs) > 0 {
+		return vs[0], true
+	}
+
+	return "", false
+}
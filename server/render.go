@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+)
+
+// String 提供字符串响应流
+func (s *Server) String(str string) {
+	s.SetContentTypeText()
+	s.Render([]byte(str))
+}
+
+// XML 提供 XML 响应流
+func (s *Server) XML(v interface{}) {
+	s.SetContentTypeXML()
+	bs, err := xml.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	s.Render(bs)
+}
+
+// JSON 提供 JSON 响应流
+func (s *Server) JSON(v interface{}) {
+	s.SetContentTypeJSON()
+	bs, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	s.Render(bs)
+}
+
+// Render 提供 http 响应流
+func (s *Server) Render(bs []byte) {
+	s.writer.WriteHeader(200)
+	_, err := s.writer.Write(bs)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// SetContentTypeText 设置 http 响应内容类型为纯文本
+func (s *Server) SetContentTypeText() {
+	s.SetContentType([]string{"text/plain; charset=utf-8"})
+}
+
+// SetContentTypeXML 设置 http 响应内容类型为 XML
+func (s *Server) SetContentTypeXML() {
+	s.SetContentType([]string{"application/xml; charset=utf-8"})
+}
+
+// SetContentTypeJSON 设置 http 响应内容类型为 JSON
+func (s *Server) SetContentTypeJSON() {
+	s.SetContentType([]string{"application/json; charset=utf-8"})
+}
+
+// SetContentType 设置 http 响应内容类型
+func (s *Server) SetContentType(vs []string) {
+	h := s.writer.Header()
+	if v := h["Content-Type"]; len(v) == 0 {
+		h["Content-Type"] = vs
+	}
+}
@@ -1,28 +1,49 @@
 package server
 
 import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
-	"github.com/gotid/wechat/msg"
-	"github.com/gotid/wechat/util"
 	"io/ioutil"
+	"mime"
+	"net/http"
 	"strconv"
+
+	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/pay"
+	"github.com/gotid/wechat/util"
 )
 
 func (s *Server) handleRequest() (reply *msg.Response, err error) {
-	s.requestRaw, err = ioutil.ReadAll(s.Request.Body)
+	s.requestRaw, err = ioutil.ReadAll(s.request.Body)
 	if err != nil {
-		return nil, fmt.Errorf("读取微信请求体失败，错误：%v", err)
+		return nil, fmt.Errorf("%w：读取微信请求体失败，错误：%v", ErrInvalidRequest, err)
+	}
+
+	// APIv3 回调为带签名头的 JSON 数据
+	if s.request.Header.Get(headerPayV3Signature) != "" {
+		return s.handlePayV3()
+	}
+
+	// 小程序等以 JSON 格式推送的常规消息
+	s.isJSON = isJSONRequest(s.request)
+	if s.isJSON {
+		return s.handleMsg()
 	}
 
 	req := requestModel{}
 	err = xml.Unmarshal(s.requestRaw, &req)
 	if err != nil {
-		err = fmt.Errorf("解析微信XML请求体失败：data=%s, err=%v", s.requestRaw, err)
+		err = fmt.Errorf("%w：解析微信XML请求体失败：data=%s, err=%v", ErrInvalidRequest, s.requestRaw, err)
 		return
 	}
 
-	if req.IsPay() {
+	if req.IsRefund() {
+		reply, err = s.handleRefund()
+	} else if req.IsPay() {
 		reply, err = s.handlePay()
 	} else {
 		reply, err = s.handleMsg()
@@ -38,52 +59,179 @@ func (s *Server) handleMsg() (reply *msg.Response, err error) {
 	s.isSafeMode = s.Query("encrypt_type") == "aes"
 
 	// 校验消息签名
-	sign := util.Signature(s.Token, s.Query("timestamp"), s.Query("nonce"))
-	if !s.debug && s.Query("signature") == sign {
-		err = fmt.Errorf("请求校验失败")
+	if err = s.checkSignature(); err != nil {
 		return
 	}
+	timestamp, nonce := s.Query("timestamp"), s.Query("nonce")
+	s.nonce = nonce
 
 	// 解密
 	if s.isSafeMode {
 		// 二进制转XML
 		var encryptedMessage msg.EncryptedMsg
-		err = xml.Unmarshal(s.requestRaw, &encryptedMessage)
+		err = s.unmarshal(s.requestRaw, &encryptedMessage)
 		if err != nil {
-			err = fmt.Errorf("解析微信加密请求体失败，错误=%v", err)
+			err = fmt.Errorf("%w：解析微信加密请求体失败，错误=%v", ErrInvalidRequest, err)
 			return
 		}
 
 		// 验证消息签名
-		timestamp := s.Query("timestamp")
-		s.timestamp, err = strconv.ParseInt(timestamp, 10, 32)
+		s.timestamp, err = strconv.ParseInt(timestamp, 10, 64)
 		if err != nil {
+			err = fmt.Errorf("%w：时间戳无效，timestamp=%s", ErrInvalidRequest, timestamp)
 			return
 		}
-		nonce := s.Query("nonce")
 		signed := util.Signature(s.Token, timestamp, nonce, encryptedMessage.Encrypt)
 		if signed != s.Query("msg_signature") {
-			err = fmt.Errorf("微信加密体签名不匹配")
+			err = fmt.Errorf("%w：微信加密体签名不匹配", ErrInvalidSignature)
 			return
 		}
 
 		// 解密
 		s.random, s.requestRaw, err = util.DecryptMsg(s.AppID, encryptedMessage.Encrypt, s.EncodingAESKey)
 		if err != nil {
-			err = fmt.Errorf("微信加密体解密失败，错误=%v", err)
+			err = fmt.Errorf("%w：微信加密体解密失败，错误=%v", ErrInvalidRequest, err)
 			return
 		}
 	}
 
-	// 调用自定义消息钩子生成回复内容
-	err = xml.Unmarshal(s.requestRaw, &s.requestMsg)
-	reply = s.msgHandler(s.Context, s.requestMsg)
-	return
+	// 解析消息
+	err = s.unmarshal(s.requestRaw, &s.requestMsg)
+	if err != nil {
+		err = fmt.Errorf("%w：解析微信消息失败：data=%s, err=%v", ErrInvalidRequest, s.requestRaw, err)
+		return
+	}
+
+	// 经中间件链调用自定义消息钩子生成回复内容
+	return s.chain()(s)
+}
+
+// 按请求的数据格式解析消息
+func (s *Server) unmarshal(data []byte, v interface{}) error {
+	if s.isJSON {
+		return json.Unmarshal(data, v)
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// 是否为 JSON 格式的请求
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
+// 校验微信请求网址中的签名，调试模式下同样校验
+func (s *Server) checkSignature() error {
+	sign := util.Signature(s.Token, s.Query("timestamp"), s.Query("nonce"))
+	if s.Query("signature") != sign {
+		return ErrInvalidSignature
+	}
+
+	return nil
 }
 
 // 处理并回复支付请求消息
 func (s *Server) handlePay() (reply *msg.Response, err error) {
-	return nil, fmt.Errorf("暂未实现支付消息处理逻辑")
+	params, err := util.XMLToMap(s.requestRaw)
+	if err != nil {
+		err = fmt.Errorf("%w：解析支付通知失败：data=%s, err=%v", ErrInvalidRequest, s.requestRaw, err)
+		return
+	}
+
+	// 通信失败时通知中不含业务数据和签名
+	if params["return_code"] != "SUCCESS" {
+		err = fmt.Errorf("%w：支付通知通信失败：return_msg=%s", ErrInvalidRequest, params["return_msg"])
+		return
+	}
+
+	// 校验签名，签名错误的通知不得进入支付钩子
+	key, err := pay.NewPay(s.Context).SignKey()
+	if err != nil {
+		err = fmt.Errorf("获取支付签名密钥失败：%w", err)
+		return
+	}
+	if !util.VerifyParamSign(params, key) {
+		err = fmt.Errorf("%w：支付通知签名不匹配", ErrInvalidSignature)
+		return
+	}
+
+	notify := &msg.PayNotify{}
+	if err = xml.Unmarshal(s.requestRaw, notify); err != nil {
+		err = fmt.Errorf("%w：解析支付通知失败：data=%s, err=%v", ErrInvalidRequest, s.requestRaw, err)
+		return
+	}
+	notify.Coupons, err = msg.ParseCoupons(params, notify.CouponCount)
+	if err != nil {
+		err = fmt.Errorf("%w：%v", ErrInvalidRequest, err)
+		return
+	}
+
+	// 调用支付钩子生成回复内容，未设置钩子时不得回复成功，以免微信停止重试
+	if s.payHandler == nil {
+		err = fmt.Errorf("%w：支付通知 out_trade_no=%s", ErrNoHandler, notify.OutTradeNo)
+		return
+	}
+	reply = s.payHandler(s.Context, notify)
+	if reply == nil {
+		reply = &msg.Response{}
+	}
+	reply.Scene = msg.ResponseScenePay
+
+	return
+}
+
+// 处理并回复退款结果通知
+func (s *Server) handleRefund() (reply *msg.Response, err error) {
+	notify := &msg.RefundNotify{}
+	if err = xml.Unmarshal(s.requestRaw, notify); err != nil {
+		err = fmt.Errorf("%w：解析退款通知失败：data=%s, err=%v", ErrInvalidRequest, s.requestRaw, err)
+		return
+	}
+	if notify.ReturnCode != "SUCCESS" {
+		err = fmt.Errorf("%w：退款通知通信失败：return_msg=%s", ErrInvalidRequest, notify.ReturnMsg)
+		return
+	}
+
+	// 解密退款结果，无法解密的通知视为伪造
+	key, err := pay.NewPay(s.Context).SignKey()
+	if err != nil {
+		err = fmt.Errorf("获取支付签名密钥失败：%w", err)
+		return
+	}
+	info, err := decryptReqInfo(notify.ReqInfo, key)
+	if err != nil {
+		err = fmt.Errorf("%w：退款通知解密失败：%v", ErrInvalidSignature, err)
+		return
+	}
+	if err = xml.Unmarshal(info, &notify.RefundResult); err != nil {
+		err = fmt.Errorf("%w：退款通知解密失败：%v", ErrInvalidSignature, err)
+		return
+	}
+
+	// 调用退款钩子生成回复内容，未设置钩子时不得回复成功，以免微信停止重试
+	if s.refundHandler == nil {
+		err = fmt.Errorf("%w：退款通知 out_refund_no=%s", ErrNoHandler, notify.OutRefundNo)
+		return
+	}
+	reply = s.refundHandler(s.Context, notify)
+	if reply == nil {
+		reply = &msg.Response{}
+	}
+	reply.Scene = msg.ResponseScenePay
+
+	return
+}
+
+// 解密退款通知中的加密信息，密钥为商户支付 key 的 32 位小写 md5
+func decryptReqInfo(reqInfo, payKey string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(reqInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := md5.Sum([]byte(payKey))
+	key := []byte(hex.EncodeToString(sum[:]))
+	return util.AesECBDecrypt(ciphertext, key)
 }
 
 // 微信请求特征模型
@@ -92,11 +240,17 @@ type requestModel struct {
 	ReturnMsg  string `xml:"return_msg"`
 	AppID      string `xml:"appid"`
 	MchID      string `xml:"mch_id"`
+	ReqInfo    string `xml:"req_info"`
+}
+
+// IsRefund 是否为微信退款结果通知
+func (m *requestModel) IsRefund() bool {
+	return m.ReqInfo != ""
 }
 
 // IsPay 是否为微信支付请求
 func (m *requestModel) IsPay() bool {
-	return m.ReturnCode == "" && m.MchID != ""
+	return m.ReturnCode != "" || m.MchID != ""
 }
 
 // IsMsg 是否为常规消息体
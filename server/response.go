@@ -1,10 +1,9 @@
 package server
 
 import (
+	"encoding/json"
 	"encoding/xml"
 	"fmt"
-
-	"reflect"
 	"strconv"
 	"time"
 
@@ -58,9 +57,12 @@ func (s *Server) buildOpenResponse(resp *msg.Response) error {
 
 // 构建支付场景的响应类型和消息
 func (s *Server) buildPayResponse(resp *msg.Response) error {
-	// 设置默认回复消息
+	// 设置默认回复类型和消息
+	if resp.Type == "" {
+		resp.Type = msg.ResponseTypeXML
+	}
 	if resp.Msg == nil {
-		s.responseMsg = msg.PayNotifyResponse{
+		resp.Msg = msg.PayNotifyResponse{
 			ReturnCode: "SUCCESS",
 			ReturnMsg:  "OK",
 		}
@@ -74,35 +76,35 @@ func (s *Server) buildPayResponse(resp *msg.Response) error {
 
 // 构建客服场景的响应类型和消息
 func (s *Server) buildKefuResponse(resp *msg.Response) error {
-	// 判断响应消息值是否为指针类型
-	respMsg := resp.Msg
-	value := reflect.ValueOf(respMsg)
-	if value.Kind().String() != "ptr" {
+	// 判断响应消息是否为被动回复消息
+	reply, ok := resp.Msg.(msg.Reply)
+	if !ok {
 		return msg.ErrUnsupportedResponse
 	}
+	if err := reply.Validate(); err != nil {
+		return err
+	}
 
-	// 设置默认响应类型
+	// 设置默认响应类型，与请求的数据格式一致
 	if resp.Type == "" {
 		resp.Type = msg.ResponseTypeXML
+		if s.isJSON {
+			resp.Type = msg.ResponseTypeJSON
+		}
 	}
+	s.responseType = resp.Type
 
-	// 设置基础回复信息(反射方法调用入参切片)
-	in := make([]reflect.Value, 1)
-	in[0] = reflect.ValueOf(s.requestMsg.FromUserName)
-	value.MethodByName("SetToUserName").Call(in)
-
-	in[0] = reflect.ValueOf(s.requestMsg.ToUserName)
-	value.MethodByName("SetFromUserName").Call(in)
-
-	in[0] = reflect.ValueOf(time.Now().Unix())
-	value.MethodByName("SetCreateTime").Call(in)
+	// 设置基础回复信息
+	reply.SetToUserName(s.requestMsg.FromUserName)
+	reply.SetFromUserName(s.requestMsg.ToUserName)
+	reply.SetCreateTime(time.Now().Unix())
 
-	s.responseMsg = respMsg
+	s.responseMsg = reply
 
 	// 安全模式加密响应消息
 	if s.isSafeMode {
-		// XML转字节切片
-		bs, err := xml.Marshal(s.responseMsg)
+		// 按响应类型序列化消息
+		bs, err := s.marshal(s.responseMsg)
 		if err != nil {
 			return err
 		}
@@ -127,3 +129,11 @@ func (s *Server) buildKefuResponse(resp *msg.Response) error {
 
 	return nil
 }
+
+// 按响应类型序列化消息
+func (s *Server) marshal(v interface{}) ([]byte, error) {
+	if s.responseType == msg.ResponseTypeJSON {
+		return json.Marshal(v)
+	}
+	return xml.Marshal(v)
+}
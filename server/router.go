@@ -0,0 +1,158 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/msg"
+)
+
+type (
+	// MsgHandlerFunc 常规消息钩子
+	MsgHandlerFunc func(*context.Context, msg.Msg) *msg.Response
+
+	// PayHandlerFunc 支付结果通知钩子
+	PayHandlerFunc func(*context.Context, *msg.PayNotify) *msg.Response
+
+	// RefundHandlerFunc 退款结果通知钩子
+	RefundHandlerFunc func(*context.Context, *msg.RefundNotify) *msg.Response
+
+	// PayV3HandlerFunc 微信支付 APIv3 支付结果通知钩子
+	PayV3HandlerFunc func(*context.Context, *msg.PayNotifyV3) *msg.Response
+
+	// RefundV3HandlerFunc 微信支付 APIv3 退款结果通知钩子
+	RefundV3HandlerFunc func(*context.Context, *msg.RefundNotifyV3) *msg.Response
+)
+
+// Router 微信消息路由器，按平台事件、消息类型和事件名称分发消息。
+// 路由器不持有平台状态，可同时挂载到多个平台的消息管理服务器上。
+type Router struct {
+	mu       sync.RWMutex
+	infos    map[msg.InfoType]MsgHandlerFunc  // 平台事件钩子
+	types    map[msg.Type]MsgHandlerFunc      // 消息类型钩子
+	events   map[msg.EventType]MsgHandlerFunc // 事件钩子
+	fallback MsgHandlerFunc                   // 兜底钩子
+}
+
+// NewRouter 返回一个新的消息路由器。
+func NewRouter() *Router {
+	return &Router{
+		infos:  map[msg.InfoType]MsgHandlerFunc{},
+		types:  map[msg.Type]MsgHandlerFunc{},
+		events: map[msg.EventType]MsgHandlerFunc{},
+	}
+}
+
+// OnInfo 注册第三方平台事件钩子
+func (r *Router) OnInfo(t msg.InfoType, h MsgHandlerFunc) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.infos[t] = h
+	return r
+}
+
+// OnType 注册指定消息类型的钩子
+func (r *Router) OnType(t msg.Type, h MsgHandlerFunc) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.types[t] = h
+	return r
+}
+
+// OnEvent 注册指定事件的钩子
+func (r *Router) OnEvent(event msg.EventType, h MsgHandlerFunc) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events[event] = h
+	return r
+}
+
+// OnText 注册文本消息钩子
+func (r *Router) OnText(h MsgHandlerFunc) *Router {
+	return r.OnType(msg.TypeText, h)
+}
+
+// OnImage 注册图片消息钩子
+func (r *Router) OnImage(h MsgHandlerFunc) *Router {
+	return r.OnType(msg.TypeImage, h)
+}
+
+// OnVoice 注册语音消息钩子
+func (r *Router) OnVoice(h MsgHandlerFunc) *Router {
+	return r.OnType(msg.TypeVoice, h)
+}
+
+// OnVideo 注册视频消息钩子
+func (r *Router) OnVideo(h MsgHandlerFunc) *Router {
+	return r.OnType(msg.TypeVideo, h)
+}
+
+// OnShortVideo 注册小视频消息钩子
+func (r *Router) OnShortVideo(h MsgHandlerFunc) *Router {
+	return r.OnType(msg.TypeShortVideo, h)
+}
+
+// OnLocation 注册地理位置消息钩子
+func (r *Router) OnLocation(h MsgHandlerFunc) *Router {
+	return r.OnType(msg.TypeLocation, h)
+}
+
+// OnLink 注册链接消息钩子
+func (r *Router) OnLink(h MsgHandlerFunc) *Router {
+	return r.OnType(msg.TypeLink, h)
+}
+
+// Fallback 注册兜底钩子，未匹配到任何钩子的消息交由其处理。
+func (r *Router) Fallback(h MsgHandlerFunc) *Router {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = h
+	return r
+}
+
+// Handle 分发消息至匹配的钩子并返回响应。
+// 平台事件总会得到开放平台场景的响应，以保证平台票据等记录逻辑正常执行。
+func (r *Router) Handle(ctx *context.Context, m msg.Msg) *msg.Response {
+	h := r.match(m)
+
+	var resp *msg.Response
+	if h != nil {
+		resp = h(ctx, m)
+	}
+
+	if m.InfoType != "" {
+		if resp == nil {
+			resp = &msg.Response{}
+		}
+		if resp.Scene == "" {
+			resp.Scene = msg.ResponseSceneOpen
+		}
+	}
+
+	return resp
+}
+
+// 匹配消息对应的钩子，优先级：平台事件 > 事件 > 消息类型 > 兜底
+func (r *Router) match(m msg.Msg) MsgHandlerFunc {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if m.InfoType != "" {
+		if h, ok := r.infos[m.InfoType]; ok {
+			return h
+		}
+		return r.fallback
+	}
+
+	if m.MsgType == msg.TypeEvent {
+		if h, ok := r.events[m.Event]; ok {
+			return h
+		}
+	}
+
+	if h, ok := r.types[m.MsgType]; ok {
+		return h
+	}
+
+	return r.fallback
+}
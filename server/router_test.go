@@ -0,0 +1,55 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/msg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouter_Handle(t *testing.T) {
+	reply := func(v string) MsgHandlerFunc {
+		return func(*context.Context, msg.Msg) *msg.Response {
+			return &msg.Response{Scene: msg.ResponseSceneKefu, Msg: v}
+		}
+	}
+
+	r := NewRouter().
+		OnText(reply("text")).
+		OnEvent(msg.EventSubscribe, reply("subscribe")).
+		OnType(msg.TypeEvent, reply("event")).
+		Fallback(reply("fallback"))
+
+	text := msg.Msg{}
+	text.MsgType = msg.TypeText
+	assert.Equal(t, "text", r.Handle(nil, text).Msg)
+
+	subscribe := msg.Msg{Event: msg.EventSubscribe}
+	subscribe.MsgType = msg.TypeEvent
+	assert.Equal(t, "subscribe", r.Handle(nil, subscribe).Msg)
+
+	click := msg.Msg{Event: msg.EventClick}
+	click.MsgType = msg.TypeEvent
+	assert.Equal(t, "event", r.Handle(nil, click).Msg)
+
+	image := msg.Msg{}
+	image.MsgType = msg.TypeImage
+	assert.Equal(t, "fallback", r.Handle(nil, image).Msg)
+}
+
+func TestRouter_HandleInfo(t *testing.T) {
+	r := NewRouter()
+
+	// 未注册的平台事件仍返回开放平台场景响应
+	resp := r.Handle(nil, msg.Msg{InfoType: msg.InfoTypeVerifyTicket})
+	assert.NotNil(t, resp)
+	assert.Equal(t, msg.ResponseSceneOpen, resp.Scene)
+
+	r.OnInfo(msg.InfoTypeAuthorized, func(*context.Context, msg.Msg) *msg.Response {
+		return &msg.Response{Msg: "authorized"}
+	})
+	resp = r.Handle(nil, msg.Msg{InfoType: msg.InfoTypeAuthorized})
+	assert.Equal(t, msg.ResponseSceneOpen, resp.Scene)
+	assert.Equal(t, "authorized", resp.Msg)
+}
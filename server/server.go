@@ -2,50 +2,101 @@ package server
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
+
 	"github.com/gotid/wechat/context"
 	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/payv3"
 )
 
 // Server 微信消息管理服务器，支持开放平台、支付、客服消息。
+// 每个微信请求对应一个服务器，请求作用域内的状态均保存于此，
+// 共享的 context.Context 不会被修改，因此可安全地并发处理推送。
 type Server struct {
 	*context.Context
 
-	debug        bool                                          // 是否调试
-	openID       string                                        // 用户 openid
-	msgHandler   func(*context.Context, msg.Msg) *msg.Response // 消息钩子
-	payHandler   func() *msg.Response                          // 支付钩子
-	requestRaw   []byte                                        // 微信请求原始数据
-	requestMsg   msg.Msg                                       // 解析后微信请求数据
-	responseType msg.ResponseType                              // 相应类型 string|xml|json
-	responseMsg  interface{}                                   // 响应数据
-	isSafeMode   bool                                          // 是否为加密模式
-	random       []byte                                        // 密文中的随机值
-	nonce        string
-	timestamp    int64
+	writer          http.ResponseWriter       // 微信请求响应流
+	request         *http.Request             // 微信请求
+	query           url.Values                // 微信请求网址参数
+	debug           bool                      // 是否调试
+	openID          string                    // 用户 openid
+	msgHandler      MsgHandlerFunc            // 消息钩子
+	middlewares     []Middleware              // 消息中间件
+	payHandler      PayHandlerFunc            // 支付钩子
+	refundHandler   RefundHandlerFunc         // 退款钩子
+	payV3Handler    PayV3HandlerFunc          // APIv3 支付钩子
+	refundV3Handler RefundV3HandlerFunc       // APIv3 退款钩子
+	payV3Certs      payv3.CertificateProvider // APIv3 平台证书
+	requestRaw      []byte                    // 微信请求原始数据
+	requestMsg      msg.Msg                   // 解析后微信请求数据
+	responseType    msg.ResponseType          // 相应类型 string|xml|json
+	responseMsg     interface{}               // 响应数据
+	isJSON          bool                      // 是否为 JSON 格式的消息
+	isSafeMode      bool                      // 是否为加密模式
+	random          []byte                    // 密文中的随机值
+	nonce           string                    // 请求随机数
+	timestamp       int64                     // 请求时间戳
 }
 
-// NewServer 返回一个新的消息管理服务器。
-func NewServer(ctx *context.Context) *Server {
+// NewServer 返回处理指定微信请求的消息管理服务器。
+func NewServer(ctx *context.Context, w http.ResponseWriter, r *http.Request) *Server {
 	return &Server{
 		Context: ctx,
+		writer:  w,
+		request: r,
+		query:   r.URL.Query(),
 	}
 }
 
-// Debug 指示是否打印调试日志
+// Debug 指示是否打印调试日志，不影响签名校验
 func (s *Server) Debug(v bool) {
 	s.debug = v
 }
 
 // SetMsgHandler 设置常规消息钩子
-func (s *Server) SetMsgHandler(h func(*context.Context, msg.Msg) *msg.Response) {
+func (s *Server) SetMsgHandler(h MsgHandlerFunc) {
 	s.msgHandler = h
 }
 
+// SetPayHandler 设置支付结果通知钩子，仅签名校验通过的通知会进入钩子
+func (s *Server) SetPayHandler(h PayHandlerFunc) {
+	s.payHandler = h
+}
+
+// SetRefundHandler 设置退款结果通知钩子，仅解密成功的通知会进入钩子
+func (s *Server) SetRefundHandler(h RefundHandlerFunc) {
+	s.refundHandler = h
+}
+
+// SetPayV3Handler 设置微信支付 APIv3 支付结果通知钩子，仅验签和解密成功的通知会进入钩子
+func (s *Server) SetPayV3Handler(h PayV3HandlerFunc) {
+	s.payV3Handler = h
+}
+
+// SetRefundV3Handler 设置微信支付 APIv3 退款结果通知钩子，仅验签和解密成功的通知会进入钩子
+func (s *Server) SetRefundV3Handler(h RefundV3HandlerFunc) {
+	s.refundV3Handler = h
+}
+
+// SetPayV3Certificates 设置校验 APIv3 回调签名的平台证书，默认自动下载和更新
+func (s *Server) SetPayV3Certificates(certs payv3.CertificateProvider) {
+	s.payV3Certs = certs
+}
+
+// SetRouter 设置消息路由器，由其按类型分发常规消息
+func (s *Server) SetRouter(r *Router) {
+	s.msgHandler = r.Handle
+}
+
 // Serve 处理微信请求并响应
 func (s *Server) Serve() error {
-	// 处理测试字符串
+	// 校验签名后回显测试字符串
 	echostr, exists := s.GetQuery("echostr")
 	if exists {
+		if err := s.checkSignature(); err != nil {
+			return err
+		}
 		s.String(echostr)
 		return nil
 	}
@@ -80,6 +131,7 @@ func (s *Server) Send() {
 	// 根据响应类型提供输出流
 	switch s.responseType {
 	case msg.ResponseTypeJSON:
+		s.JSON(s.responseMsg)
 	case msg.ResponseTypeXML:
 		s.XML(s.responseMsg)
 	case msg.ResponseTypeString:
@@ -89,6 +141,16 @@ func (s *Server) Send() {
 	}
 }
 
+// Writer 返回微信请求的响应流
+func (s *Server) Writer() http.ResponseWriter {
+	return s.writer
+}
+
+// Request 返回微信请求
+func (s *Server) Request() *http.Request {
+	return s.request
+}
+
 // OpenID 获取请求者 openID
 func (s *Server) OpenID() string {
 	return s.openID
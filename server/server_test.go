@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gotid/wechat/cache"
+	"github.com/gotid/wechat/context"
+	"github.com/gotid/wechat/msg"
+	"github.com/gotid/wechat/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestContext() *context.Context {
+	return &context.Context{
+		AppID:          "wx0123456789",
+		Token:          "token",
+		EncodingAESKey: base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("k"), 32))[:43],
+		Cache:          cache.NewMemory(),
+	}
+}
+
+// 构建安全模式下的微信推送请求
+func newEncryptedRequest(t *testing.T, ctx *context.Context, from string) *http.Request {
+	raw := fmt.Sprintf("<xml><ToUserName><![CDATA[gh_test]]></ToUserName>"+
+		"<FromUserName><![CDATA[%s]]></FromUserName><CreateTime>%d</CreateTime>"+
+		"<MsgType><![CDATA[text]]></MsgType></xml>", from, time.Now().Unix())
+
+	encrypted, err := util.EncryptMsg([]byte(from[:16]), []byte(raw), ctx.AppID, ctx.EncodingAESKey)
+	assert.Nil(t, err)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := from
+	body, err := xml.Marshal(msg.EncryptedMsg{ToUserName: "gh_test", Encrypt: string(encrypted)})
+	assert.Nil(t, err)
+
+	query := url.Values{}
+	query.Set("timestamp", timestamp)
+	query.Set("nonce", nonce)
+	query.Set("openid", from)
+	query.Set("encrypt_type", "aes")
+	query.Set("signature", util.Signature(ctx.Token, timestamp, nonce))
+	query.Set("msg_signature", util.Signature(ctx.Token, timestamp, nonce, string(encrypted)))
+
+	return httptest.NewRequest(http.MethodPost, "/?"+query.Encode(), bytes.NewReader(body))
+}
+
+func TestServer_Concurrent(t *testing.T) {
+	ctx := newTestContext()
+	router := NewRouter().OnText(func(_ *context.Context, m msg.Msg) *msg.Response {
+		time.Sleep(time.Millisecond)
+		return &msg.Response{
+			Scene: msg.ResponseSceneKefu,
+			Msg:   msg.NewText(string(m.FromUserName)),
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			from := fmt.Sprintf("openid_%016d", i)
+			w := httptest.NewRecorder()
+			s := NewServer(ctx, w, newEncryptedRequest(t, ctx, from))
+			s.SetRouter(router)
+			if !assert.Nil(t, s.Serve()) {
+				return
+			}
+			s.Send()
+
+			// 回复须发送给本次请求的用户，且使用本次请求的随机数签名
+			var resp struct {
+				Encrypt      string `xml:"Encrypt"`
+				MsgSignature string `xml:"MsgSignature"`
+				TimeStamp    int64  `xml:"TimeStamp"`
+				Nonce        string `xml:"Nonce"`
+			}
+			assert.Nil(t, xml.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, from, resp.Nonce)
+			timestamp := strconv.FormatInt(resp.TimeStamp, 10)
+			assert.Equal(t, util.Signature(ctx.Token, timestamp, resp.Nonce, resp.Encrypt), resp.MsgSignature)
+
+			_, raw, err := util.DecryptMsg(ctx.AppID, resp.Encrypt, ctx.EncodingAESKey)
+			assert.Nil(t, err)
+			var reply msg.Text
+			assert.Nil(t, xml.Unmarshal(raw, &reply))
+			assert.Equal(t, msg.CDATA(from), reply.ToUserName)
+			assert.Equal(t, msg.CDATA(from), reply.Content)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestServer_JSON(t *testing.T) {
+	ctx := newTestContext()
+	router := NewRouter().OnEvent(msg.EventUserEnterTempSession, func(_ *context.Context, m msg.Msg) *msg.Response {
+		assert.Equal(t, "pages/index", m.SessionFrom)
+		return &msg.Response{Scene: msg.ResponseSceneKefu, Msg: msg.NewTransferCustomerService()}
+	})
+
+	raw := `{"ToUserName":"gh_test","FromUserName":"openid_json","CreateTime":1482048670,"MsgType":"event","Event":"user_enter_tempsession","SessionFrom":"pages/index"}`
+	encrypted, err := util.EncryptMsg([]byte("0123456789abcdef"), []byte(raw), ctx.AppID, ctx.EncodingAESKey)
+	assert.Nil(t, err)
+
+	timestamp, nonce := strconv.FormatInt(time.Now().Unix(), 10), "nonce"
+	query := url.Values{}
+	query.Set("timestamp", timestamp)
+	query.Set("nonce", nonce)
+	query.Set("encrypt_type", "aes")
+	query.Set("signature", util.Signature(ctx.Token, timestamp, nonce))
+	query.Set("msg_signature", util.Signature(ctx.Token, timestamp, nonce, string(encrypted)))
+	body := fmt.Sprintf(`{"ToUserName":"gh_test","Encrypt":"%s"}`, encrypted)
+	r := httptest.NewRequest(http.MethodPost, "/?"+query.Encode(), bytes.NewReader([]byte(body)))
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	s := NewServer(ctx, w, r)
+	s.SetRouter(router)
+	assert.Nil(t, s.Serve())
+	s.Send()
+
+	// 回复为加密的 JSON 消息
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+	var resp msg.EncryptedResponseMsg
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, util.Signature(ctx.Token, timestamp, nonce, resp.EncryptedMsg), resp.MsgSignature)
+
+	_, plaintext, err := util.DecryptMsg(ctx.AppID, resp.EncryptedMsg, ctx.EncodingAESKey)
+	assert.Nil(t, err)
+	var reply map[string]interface{}
+	assert.Nil(t, json.Unmarshal(plaintext, &reply))
+	assert.Equal(t, "openid_json", reply["ToUserName"])
+	assert.Equal(t, string(msg.TypeTransferKf), reply["MsgType"])
+}
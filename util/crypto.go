@@ -318,6 +318,23 @@ func AesECBDecrypt(ciphertext []byte, aesKey []byte) ([]byte, error) {
 	return PKCS5UnPadding(ciphertext), nil
 }
 
+// AesGCMDecrypt 使用 AEAD_AES_256_GCM 解密数据，密文末尾包含认证标签
+func AesGCMDecrypt(ciphertext, aesKey, nonce, associatedData []byte) ([]byte, error) {
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	// 随机串长度不符时 gcm.Open 会 panic，须事先校验
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("随机串长度须为 %d 字节，实际为 %d 字节", gcm.NonceSize(), len(nonce))
+	}
+	return gcm.Open(nil, nonce, ciphertext, associatedData)
+}
+
 // PKCS5Padding -
 func PKCS5Padding(ciphertext []byte, blockSize int) []byte {
 	padding := blockSize - len(ciphertext)%blockSize
@@ -328,7 +345,13 @@ func PKCS5Padding(ciphertext []byte, blockSize int) []byte {
 // PKCS5UnPadding -
 func PKCS5UnPadding(origData []byte) []byte {
 	length := len(origData)
+	if length == 0 {
+		return origData
+	}
 	unPadding := int(origData[length-1])
+	if unPadding > length {
+		return origData
+	}
 	return origData[:(length - unPadding)]
 }
 
@@ -358,3 +381,18 @@ func OrderParam(p map[string]string, bizKey string) (returnStr string) {
 	returnStr = buf.String()
 	return
 }
+
+// VerifyParamSign 校验所传参数中的签名
+func VerifyParamSign(p map[string]string, key string) bool {
+	sign, ok := p["sign"]
+	if !ok || sign == "" {
+		return false
+	}
+
+	expected, err := ParamSign(p, key)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal([]byte(sign), []byte(expected))
+}
@@ -0,0 +1,29 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAesGCMDecrypt(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	nonce := []byte("0123456789ab")
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	ciphertext := gcm.Seal(nil, nonce, []byte(`{"out_trade_no":"20210101"}`), []byte("transaction"))
+
+	plaintext, err := AesGCMDecrypt(ciphertext, key, nonce, []byte("transaction"))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"out_trade_no":"20210101"}`, string(plaintext))
+
+	// 随机串长度不符时返回错误而非 panic
+	for _, bad := range []string{"short", "0123456789abcdef"} {
+		assert.NotPanics(t, func() {
+			_, err = AesGCMDecrypt(ciphertext, key, []byte(bad), []byte("transaction"))
+		})
+		assert.NotNil(t, err)
+	}
+}
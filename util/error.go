@@ -2,16 +2,46 @@ package util
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
+// 微信接口常见的错误码
+// https://developers.weixin.qq.com/doc/oplatform/Return_codes/Return_code_descriptions_new.html
+const (
+	ErrCodeSystemBusy         int64 = -1    // 系统繁忙，稍后重试
+	ErrCodeInvalidCredential  int64 = 40001 // 访问令牌无效或不是最新的，如已被其他系统刷新
+	ErrCodeInvalidAppID       int64 = 40013 // 不合法的 AppID
+	ErrCodeInvalidAccessToken int64 = 40014 // 不合法的访问令牌
+	ErrCodeAccessTokenExpired int64 = 42001 // 访问令牌已过期
+	ErrCodeAPIQuotaExceeded   int64 = 45009 // 接口调用超过每日限额
+	ErrCodeAPIMinuteQuota     int64 = 45011 // 接口调用过于频繁，须下一分钟再试
+	ErrCodeAPIUnauthorized    int64 = 48001 // 接口未授权
+	ErrCodeTicketExpired      int64 = 61005 // 第三方平台票据已过期
+	ErrCodeTicketInvalid      int64 = 61006 // 第三方平台票据无效
+)
+
+// 常见的微信接口错误，可通过 errors.Is 判断
+var (
+	ErrSystemBusy         = &WechatError{ErrCode: ErrCodeSystemBusy}
+	ErrInvalidCredential  = &WechatError{ErrCode: ErrCodeInvalidCredential}
+	ErrInvalidAppID       = &WechatError{ErrCode: ErrCodeInvalidAppID}
+	ErrInvalidAccessToken = &WechatError{ErrCode: ErrCodeInvalidAccessToken}
+	ErrAccessTokenExpired = &WechatError{ErrCode: ErrCodeAccessTokenExpired}
+	ErrAPIQuotaExceeded   = &WechatError{ErrCode: ErrCodeAPIQuotaExceeded}
+	ErrAPIMinuteQuota     = &WechatError{ErrCode: ErrCodeAPIMinuteQuota}
+	ErrAPIUnauthorized    = &WechatError{ErrCode: ErrCodeAPIUnauthorized}
+)
+
 // WechatError 是微信接口通用错误结构体。
 type WechatError struct {
 	ErrCode int64  `json:"errcode"`
 	ErrMsg  string `json:"errmsg,omitempty"`
+	APIName string `json:"-"` // 出错的接口名称
 }
 
 // UnknownError 返回一个未知错误信息。
+// err 为 nil 时返回 nil 的 *WechatError，切勿直接赋值给 error 接口，否则不等于 nil。
 func UnknownError(err error) *WechatError {
 	if err != nil {
 		return &WechatError{
@@ -22,7 +52,7 @@ func UnknownError(err error) *WechatError {
 	return nil
 }
 
-// TryDecodeError 尝试解码响应错误。
+// TryDecodeError 尝试解码响应错误，接口返回错误码时返回 *WechatError。
 func TryDecodeError(data []byte, apiName string) (err error) {
 	var commonErr WechatError
 	err = json.Unmarshal(data, &commonErr)
@@ -30,12 +60,30 @@ func TryDecodeError(data []byte, apiName string) (err error) {
 		return err
 	}
 
-	if commonErr.ErrCode != 0 {
-		return fmt.Errorf("微信接口 %s 错误：errcode=%d, errmsg=%s",
-			apiName, commonErr.ErrCode, commonErr.ErrMsg)
+	return commonErr.Err(apiName)
+}
+
+func (e *WechatError) Error() string {
+	if e.APIName == "" {
+		return fmt.Sprintf("微信接口错误：errcode=%d, errmsg=%s", e.ErrCode, e.ErrMsg)
 	}
+	return fmt.Sprintf("微信接口 %s 错误：errcode=%d, errmsg=%s", e.APIName, e.ErrCode, e.ErrMsg)
+}
 
-	return nil
+// Is 错误码相同时视为同一错误
+func (e *WechatError) Is(target error) bool {
+	t, ok := target.(*WechatError)
+	return ok && t.ErrCode == e.ErrCode
+}
+
+// Err 返回标注接口名称的错误，成功时返回 nil
+func (e *WechatError) Err(apiName string) error {
+	if e.Success() {
+		return nil
+	}
+	err := *e
+	err.APIName = apiName
+	return &err
 }
 
 func (e *WechatError) Success() bool {
@@ -44,3 +92,45 @@ func (e *WechatError) Success() bool {
 	}
 	return false
 }
+
+// ErrCode 返回错误链中的微信接口错误码，不是微信接口错误时返回 0
+func ErrCode(err error) int64 {
+	var e *WechatError
+	if errors.As(err, &e) {
+		return e.ErrCode
+	}
+	return 0
+}
+
+// IsTokenError 是否为访问令牌失效，刷新令牌后可重试
+func IsTokenError(err error) bool {
+	switch ErrCode(err) {
+	case ErrCodeInvalidCredential, ErrCodeInvalidAccessToken, ErrCodeAccessTokenExpired:
+		return true
+	}
+	return false
+}
+
+// IsTicketError 是否为第三方平台票据失效，须等待微信推送新票据，刷新访问令牌无法恢复
+func IsTicketError(err error) bool {
+	code := ErrCode(err)
+	return code == ErrCodeTicketExpired || code == ErrCodeTicketInvalid
+}
+
+// IsQuotaExceeded 是否为接口调用超过限额
+func IsQuotaExceeded(err error) bool {
+	code := ErrCode(err)
+	return code == ErrCodeAPIQuotaExceeded || code == ErrCodeAPIMinuteQuota
+}
+
+// IsRetryable 是否可稍后以相同参数重试：系统繁忙、分钟级限频及令牌失效（须先刷新令牌）
+func IsRetryable(err error) bool {
+	code := ErrCode(err)
+	return code == ErrCodeSystemBusy || code == ErrCodeAPIMinuteQuota || IsTokenError(err)
+}
+
+// IsAuditError 是否为代码管理及审核相关错误（850xx、860xx），如审核中、提审次数超限等
+func IsAuditError(err error) bool {
+	code := ErrCode(err)
+	return code >= 85000 && code < 85100 || code >= 86000 && code < 86100
+}
@@ -0,0 +1,47 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTryDecodeError(t *testing.T) {
+	assert.Nil(t, TryDecodeError([]byte(`{"errcode":0,"errmsg":"ok"}`), "Commit"))
+
+	err := TryDecodeError([]byte(`{"errcode":85009,"errmsg":"already has one auditing version"}`), "SubmitAudit")
+	assert.EqualError(t, err, "微信接口 SubmitAudit 错误：errcode=85009, errmsg=already has one auditing version")
+
+	var e *WechatError
+	wrapped := fmt.Errorf("提交审核失败：%w", err)
+	assert.True(t, errors.As(wrapped, &e))
+	assert.Equal(t, "SubmitAudit", e.APIName)
+	assert.Equal(t, int64(85009), ErrCode(wrapped))
+	assert.True(t, IsAuditError(wrapped))
+	assert.False(t, IsRetryable(wrapped))
+}
+
+func TestErrorPredicates(t *testing.T) {
+	busy := &WechatError{ErrCode: ErrCodeSystemBusy, APIName: "GetPage"}
+	assert.True(t, errors.Is(busy, ErrSystemBusy))
+	assert.False(t, errors.Is(busy, ErrAPIQuotaExceeded))
+	assert.True(t, IsRetryable(busy))
+
+	expired := fmt.Errorf("刷新失败：%w", &WechatError{ErrCode: ErrCodeAccessTokenExpired})
+	assert.True(t, IsTokenError(expired))
+	assert.True(t, IsRetryable(expired))
+
+	quota := &WechatError{ErrCode: ErrCodeAPIQuotaExceeded}
+	assert.True(t, IsQuotaExceeded(quota))
+	assert.False(t, IsRetryable(quota))
+
+	ticket := &WechatError{ErrCode: ErrCodeTicketExpired}
+	assert.True(t, IsTicketError(ticket))
+	assert.False(t, IsTokenError(ticket))
+	assert.False(t, IsRetryable(ticket))
+
+	assert.False(t, IsTokenError(errors.New("网络错误")))
+	assert.Equal(t, int64(0), ErrCode(nil))
+}
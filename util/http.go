@@ -8,8 +8,15 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 )
 
+// DefaultHTTPTimeout 微信接口请求的默认超时时长，含读取响应
+const DefaultHTTPTimeout = 30 * time.Second
+
+// HTTPClient 发送微信接口及微信支付请求的客户端，可替换以设置超时、代理或在测试中指向本地服务
+var HTTPClient = &http.Client{Timeout: DefaultHTTPTimeout}
+
 // PostJSON 发送 JSON 数据请求。
 func PostJSON(url string, object interface{}) ([]byte, error) {
 	body := new(bytes.Buffer)
@@ -18,7 +25,7 @@ func PostJSON(url string, object interface{}) ([]byte, error) {
 	if err := encoder.Encode(object); err != nil {
 		return nil, err
 	}
-	resp, err := http.Post(url, "application/json;charset=utf-8", body)
+	resp, err := HTTPClient.Post(url, "application/json;charset=utf-8", body)
 	if err != nil {
 		return nil, err
 	}
@@ -32,7 +39,7 @@ func PostJSON(url string, object interface{}) ([]byte, error) {
 
 // HTTPGet 网络拉取请求
 func HTTPGet(uri string) ([]byte, error) {
-	resp, err := http.Get(uri)
+	resp, err := HTTPClient.Get(uri)
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +55,7 @@ func HTTPGet(uri string) ([]byte, error) {
 // HTTPPost 网络投递请求
 func HTTPPost(uri string, data string) ([]byte, error) {
 	bytes.NewBuffer([]byte(data))
-	resp, err := http.Get(uri)
+	resp, err := HTTPClient.Get(uri)
 	if err != nil {
 		return nil, err
 	}
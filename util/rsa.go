@@ -0,0 +1,84 @@
+package util
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ParseRSAPrivateKey 解析 PEM 格式的 RSA 私钥，支持 PKCS#8 和 PKCS#1
+func ParseRSAPrivateKey(pemData []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("私钥不是有效的 PEM 格式")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败：%w", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("私钥不是 RSA 私钥")
+	}
+	return rsaKey, nil
+}
+
+// ParseCertificate 解析 PEM 格式的 X.509 证书
+func ParseCertificate(pemData []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("证书不是有效的 PEM 格式")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// RSASignSHA256 使用 SHA256-RSA 签名，返回 base64 编码的签名
+func RSASignSHA256(key *rsa.PrivateKey, message []byte) (string, error) {
+	hashed := sha256.Sum256(message)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+// RSAVerifySHA256 校验 base64 编码的 SHA256-RSA 签名
+func RSAVerifySHA256(key *rsa.PublicKey, message []byte, signature string) error {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("签名不是有效的 base64 编码：%w", err)
+	}
+
+	hashed := sha256.Sum256(message)
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sig)
+}
+
+// RSAEncryptOAEP 使用 RSAES-OAEP（SHA1）加密，返回 base64 编码的密文
+func RSAEncryptOAEP(key *rsa.PublicKey, plaintext []byte) (string, error) {
+	ciphertext, err := rsa.EncryptOAEP(sha1.New(), rand.Reader, key, plaintext, nil)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// RSADecryptOAEP 解密 base64 编码的 RSAES-OAEP（SHA1）密文
+func RSADecryptOAEP(key *rsa.PrivateKey, ciphertext string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("密文不是有效的 base64 编码：%w", err)
+	}
+	return rsa.DecryptOAEP(sha1.New(), rand.Reader, key, data, nil)
+}
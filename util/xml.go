@@ -0,0 +1,71 @@
+package util
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+)
+
+// XMLToMap 将微信支付的单层 XML 数据解析为键值对。
+func XMLToMap(data []byte) (map[string]string, error) {
+	m := map[string]string{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var (
+		key   string
+		depth int
+		value strings.Builder
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				key = t.Name.Local
+				value.Reset()
+			}
+		case xml.CharData:
+			if depth == 2 {
+				value.Write(t)
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				m[key] = strings.TrimSpace(value.String())
+			}
+			depth--
+		}
+	}
+}
+
+// MapToXML 将键值对按键名排序后编码为微信支付的单层 XML 数据。
+func MapToXML(m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("<xml>")
+	for _, k := range keys {
+		if m[k] == "" {
+			continue
+		}
+		buf.WriteString("<" + k + ">")
+		_ = xml.EscapeText(&buf, []byte(m[k]))
+		buf.WriteString("</" + k + ">")
+	}
+	buf.WriteString("</xml>")
+
+	return buf.Bytes()
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXMLToMap(t *testing.T) {
+	m, err := XMLToMap([]byte(`<xml>
+<return_code><![CDATA[SUCCESS]]></return_code>
+<total_fee>100</total_fee>
+<attach><![CDATA[a&b]]></attach>
+</xml>`))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{
+		"return_code": "SUCCESS",
+		"total_fee":   "100",
+		"attach":      "a&b",
+	}, m)
+}
+
+func TestMapToXML(t *testing.T) {
+	p := map[string]string{"b": "<2>", "a": "1", "c": ""}
+	bs := MapToXML(p)
+	assert.Equal(t, "<xml><a>1</a><b>&lt;2&gt;</b></xml>", string(bs))
+
+	m, err := XMLToMap(bs)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"a": "1", "b": "<2>"}, m)
+}
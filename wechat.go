@@ -3,6 +3,8 @@ package wechat
 import (
 	"github.com/gotid/wechat/context"
 	"github.com/gotid/wechat/open"
+	"github.com/gotid/wechat/pay"
+	"github.com/gotid/wechat/payv3"
 	"github.com/gotid/wechat/server"
 	"net/http"
 )
@@ -19,12 +21,20 @@ func Get(ctx *context.Context) *WeChat {
 
 // Server 返回消息管理服务器
 func (wc *WeChat) Server(w http.ResponseWriter, r *http.Request) *server.Server {
-	wc.Context.Writer = w
-	wc.Context.Request = r
-	return server.NewServer(wc.Context)
+	return server.NewServer(wc.Context, w, r)
 }
 
 // OpenPlatform 返回开放平台控制器
 func (wc *WeChat) OpenPlatform() *open.Open {
 	return open.NewPlatform(wc.Context)
 }
+
+// Pay 返回微信支付控制器
+func (wc *WeChat) Pay() *pay.Pay {
+	return pay.NewPay(wc.Context)
+}
+
+// PayV3 返回微信支付 APIv3 控制器，未指定平台证书时自动下载和更新
+func (wc *WeChat) PayV3(certs payv3.CertificateProvider) *payv3.Pay {
+	return payv3.NewPay(wc.Context, certs)
+}